@@ -0,0 +1,70 @@
+// Command fakeinfo runs a stub of the external music info API, so the
+// service can be developed and tested without the real upstream.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"songs_lib/internal/fakeinfo"
+	"time"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("fakeinfo", flag.ContinueOnError)
+	addr := flags.String("addr", ":8081", "address to listen on")
+	fixturesPath := flags.String("fixtures", "", "JSON file or directory of fixtures, the bundled ones by default")
+	latency := flags.Duration("latency", 0, "delay before every response")
+	jitter := flags.Duration("jitter", 0, "random extra delay up to this value")
+	errorRate := flags.Float64("error-rate", 0, "share of requests that fail, from 0 to 1")
+	errorStatus := flags.Int("error-status", http.StatusInternalServerError, "status of failed requests")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	var fixtures []fakeinfo.Fixture
+	var err error
+	if *fixturesPath != "" {
+		fixtures, err = fakeinfo.LoadFixtures(*fixturesPath)
+	} else {
+		fixtures, err = fakeinfo.DefaultFixtures()
+	}
+	if err != nil {
+		return err
+	}
+
+	server := fakeinfo.NewServer(fixtures, fakeinfo.Options{
+		Latency:     *latency,
+		Jitter:      *jitter,
+		ErrorRate:   *errorRate,
+		ErrorStatus: *errorStatus,
+	})
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	log.Info("Starting fake info API",
+		slog.String("addr", *addr),
+		slog.Int("fixtures", len(fixtures)),
+		slog.Duration("latency", *latency),
+		slog.Float64("error_rate", *errorRate),
+	)
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return httpServer.ListenAndServe()
+}
@@ -13,18 +13,66 @@ import (
 	"github.com/kelseyhightower/envconfig"
 )
 
-func run() error {
+const usage = `Usage: songs_lib [command] [args]
+
+Commands:
+  serve                 start the HTTP server (default)
+  migrate up            apply all pending migrations
+  migrate down [N]      roll back N migrations (default 1)
+  migrate version       print the current migration version
+  migrate force V       set the migration version without running migrations
+  seed FILE             add songs from a JSON file
+`
+
+const legacyExternalAPIEnv = "EXTERNALAPI"
+
+func loadConfig() (config.Config, error) {
+	cfg := config.Config{}
+
 	err := godotenv.Load()
 	if err != nil {
-		return fmt.Errorf("error loading environment: %v", err)
+		return cfg, fmt.Errorf("error loading environment: %v", err)
 	}
 
-	cfg := config.Config{}
 	if err := envconfig.Process("", &cfg); err != nil {
-		return fmt.Errorf("error loading environment: %v", err)
+		return cfg, fmt.Errorf("error loading environment: %v", err)
 	}
 
-	fmt.Printf("%+v\n", cfg)
+	// before the external API had its own settings EXTERNALAPI was its URL
+	if cfg.ExternalAPI.URL == "" {
+		cfg.ExternalAPI.URL = os.Getenv(legacyExternalAPIEnv)
+	}
+
+	return cfg, nil
+}
+
+func run(args []string) error {
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		return serve()
+	case "migrate":
+		return runMigrate(args)
+	case "seed":
+		return runSeed(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func serve() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
 
 	log, err := logger.SetupLogger(&cfg)
 	if err != nil {
@@ -32,15 +80,18 @@ func run() error {
 	}
 
 	log.Info("Config read success")
-	app, err := app.NewApp(log, cfg.HTTP, cfg.Storage, cfg.ExternalAPI)
+	if os.Getenv(legacyExternalAPIEnv) != "" {
+		log.Warn("EXTERNALAPI is deprecated, set EXTERNALAPI_URL instead")
+	}
+	app, err := app.NewApp(log, cfg.HTTP, cfg.Storage, cfg.ExternalAPI, cfg.Enrichment, cfg.Refresh, cfg.Idempotency, cfg.Trash)
 	if err != nil {
-		log.Error("error creating app: %v", err)
+		log.Error("error creating app", logger.Err(err))
 		return err
 	}
 	defer app.DB.Close()
 
 	if err := app.Run(); err != nil {
-		log.Error("error running app: %v", err)
+		log.Error("error running app", logger.Err(err))
 		return err
 	}
 
@@ -68,7 +119,7 @@ func run() error {
 // @externalDocs.description	OpenAPI
 // @externalDocs.url			https://swagger.io/resources/open-api/
 func main() {
-	if err := run(); err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		fmt.Printf("error running service: %v\n", err)
 		os.Exit(1)
 	}
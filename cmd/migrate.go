@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"songs_lib/internal/app"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate: missing subcommand (up, down, version, force)")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	m, err := app.NewMigrate(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("migrate down: invalid number of steps %q", args[1])
+			}
+		}
+		err = m.Steps(-steps)
+	case "version":
+	case "force":
+		if len(args) < 2 {
+			return errors.New("migrate force: missing version")
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("migrate force: invalid version %q", args[1])
+		}
+		err = m.Force(version)
+	default:
+		return fmt.Errorf("migrate: unknown subcommand %q", args[0])
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("no change")
+	} else if err != nil {
+		return fmt.Errorf("migrate %s: %w", args[0], err)
+	}
+
+	return printVersion(m)
+}
+
+func printVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("version: none")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("migrate version: %w", err)
+	}
+
+	fmt.Printf("version: %d, dirty: %t\n", version, dirty)
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"songs_lib/internal/app"
+	"songs_lib/internal/service"
+	external "songs_lib/internal/web/external"
+	"songs_lib/pkg/logger"
+	"time"
+)
+
+// seedSong uses the same field names and date format as the external API.
+type seedSong struct {
+	Group       string `json:"group"`
+	Song        string `json:"song"`
+	ReleaseDate string `json:"releaseDate"`
+	Link        string `json:"link"`
+	Text        string `json:"text"`
+}
+
+func runSeed(args []string) error {
+	if len(args) == 0 {
+		return errors.New("seed: missing file")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+
+	var songs []seedSong
+	if err := json.Unmarshal(data, &songs); err != nil {
+		return fmt.Errorf("seed: failed to decode %s: %w", args[0], err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	log, err := logger.SetupLogger(&cfg)
+	if err != nil {
+		return fmt.Errorf("error setup logger: %v", err)
+	}
+
+	db, err := app.NewStorage(log, cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	defer db.Close()
+
+	providers, err := external.NewProviders(log, cfg.ExternalAPI)
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	songService := service.NewSongService(log, db, providers)
+
+	var failed int
+	for _, song := range songs {
+		releaseDate, err := time.Parse("02.01.2006", song.ReleaseDate)
+		if err != nil {
+			fmt.Printf("skip %s - %s: invalid release date %q\n", song.Group, song.Song, song.ReleaseDate)
+			failed++
+			continue
+		}
+
+		id, err := songService.AddSong(context.Background(), song.Group, song.Song, song.Link, releaseDate, song.Text)
+		if err != nil {
+			fmt.Printf("skip %s - %s: %v\n", song.Group, song.Song, err)
+			failed++
+			continue
+		}
+		fmt.Printf("added %s - %s (id %d)\n", song.Group, song.Song, id)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("seed: %d of %d songs failed", failed, len(songs))
+	}
+	return nil
+}
@@ -7,7 +7,11 @@ type Config struct {
 	Env         string  `env:"ENV" envDefault:"local"`
 	HTTP        HTTP    `env:"HTTP"`
 	Storage     Storage `env:"STORAGE"`
-	ExternalAPI string  `env:"EXTERNALAPI"`
+	ExternalAPI ExternalAPI
+	Enrichment  Enrichment  `env:"ENRICHMENT"`
+	Refresh     Refresh     `env:"REFRESH"`
+	Idempotency Idempotency `env:"IDEMPOTENCY"`
+	Trash       Trash       `env:"TRASH"`
 }
 
 type HTTP struct {
@@ -16,5 +20,67 @@ type HTTP struct {
 }
 
 type Storage struct {
-	Path string `env:"PATH" required:"true"`
+	Path           string `env:"PATH" required:"true"`
+	SkipMigrations bool   `env:"SKIPMIGRATIONS"`
+}
+
+// ExternalAPI configures the client of the music info API. Requests that
+// fail with a network error or a 5xx status are retried with exponential
+// backoff; after BreakerThreshold consecutive failures the client fails fast
+// for BreakerCooldown. FallbackURL and File add a second API with the same
+// settings and a local JSON file, asked in that order for the fields the
+// primary API didn't return. The enrichment workers cache the details of a
+// song for CacheTTL and that it is unknown for CacheNotFoundTTL; a zero TTL
+// disables that part of the cache. Once CacheSize songs are cached the
+// oldest one is dropped.
+type ExternalAPI struct {
+	URL              string        `env:"URL"`
+	FallbackURL      string        `env:"FALLBACKURL"`
+	File             string        `env:"FILE"`
+	Timeout          time.Duration `env:"TIMEOUT" default:"5s"`
+	MaxRetries       int           `env:"MAXRETRIES" default:"2"`
+	RetryBaseDelay   time.Duration `env:"RETRYBASEDELAY" default:"200ms"`
+	RetryMaxDelay    time.Duration `env:"RETRYMAXDELAY" default:"2s"`
+	BreakerThreshold int           `env:"BREAKERTHRESHOLD" default:"5"`
+	BreakerCooldown  time.Duration `env:"BREAKERCOOLDOWN" default:"30s"`
+	CacheTTL         time.Duration `env:"CACHETTL" default:"10m"`
+	CacheNotFoundTTL time.Duration `env:"CACHENOTFOUNDTTL" default:"1m"`
+	CacheSize        int           `env:"CACHESIZE" default:"10000"`
+}
+
+// Enrichment configures the workers that fetch details of new songs from the
+// external API. A failed job is retried after RetryDelay, doubled for every
+// attempt, until MaxAttempts is reached. A job claimed by a worker that
+// stopped is picked up again after Lease.
+type Enrichment struct {
+	Workers      int           `env:"WORKERS" default:"2"`
+	PollInterval time.Duration `env:"POLLINTERVAL" default:"1s"`
+	MaxAttempts  int           `env:"MAXATTEMPTS" default:"5"`
+	RetryDelay   time.Duration `env:"RETRYDELAY" default:"30s"`
+	Lease        time.Duration `env:"LEASE" default:"2m"`
+}
+
+// Refresh configures the scheduled re-sync of stored songs with the external
+// API. Every Interval all songs are fetched again, BatchSize at a time; a zero
+// Interval disables it.
+type Refresh struct {
+	Interval  time.Duration `env:"INTERVAL" default:"24h"`
+	BatchSize int           `env:"BATCHSIZE" default:"100"`
+}
+
+// Idempotency configures how long the response to a request with an
+// Idempotency-Key header is kept for replaying. A key whose request hasn't
+// finished within Lease, because it is stuck or its server stopped, can be
+// taken over by a retry.
+type Idempotency struct {
+	TTL   time.Duration `env:"TTL" default:"24h"`
+	Lease time.Duration `env:"LEASE" default:"1m"`
+}
+
+// Trash configures how long deleted songs can be restored. Every
+// PurgeInterval the songs deleted more than Retention ago are removed for
+// good; a zero Retention keeps them forever.
+type Trash struct {
+	Retention     time.Duration `env:"RETENTION" default:"720h"`
+	PurgeInterval time.Duration `env:"PURGEINTERVAL" default:"1h"`
 }
@@ -30,6 +30,16 @@ const docTemplate = `{
                         "name": "group",
                         "in": "query"
                     },
+                    {
+                        "enum": [
+                            "fuzzy",
+                            "exact"
+                        ],
+                        "type": "string",
+                        "description": "Сравнение названия группы: fuzzy — по подстроке без учёта регистра (по умолчанию), exact — точное совпадение",
+                        "name": "group_match",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Название песни",
@@ -38,10 +48,52 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Дата релиза",
+                        "description": "ID песен через запятую, например 1,2,3",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата релиза в формате YYYY-MM-DD",
                         "name": "release_date",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Дата релиза не раньше, YYYY-MM-DD",
+                        "name": "release_date_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата релиза не позже, YYYY-MM-DD",
+                        "name": "release_date_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Добавлены после момента, RFC 3339 или YYYY-MM-DD",
+                        "name": "inserted_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Добавлены до момента, RFC 3339 или YYYY-MM-DD",
+                        "name": "inserted_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только песни со ссылкой (true) или без неё (false)",
+                        "name": "has_link",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только песни с текстом (true) или без него (false)",
+                        "name": "has_lyrics",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "description": "Количество записей на странице",
@@ -53,6 +105,24 @@ const docTemplate = `{
                         "description": "Смещение для пагинации",
                         "name": "offset",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Сортировка через запятую, минус перед полем — по убыванию. Поля: release_date, inserted_at, name, group. По умолчанию release_date",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из next_cursor, выданный для той же сортировки",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Связанные данные через запятую: verses. Без параметра куплеты включаются, пустое значение возвращает только метаданные",
+                        "name": "include",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -60,6 +130,16 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/dto.LibraryDTO"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на первую, предыдущую, следующую и последнюю страницы"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Общее количество песен, подходящих под фильтры"
+                            }
                         }
                     },
                     "400": {
@@ -112,9 +192,16 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.SongDTO"
+                            "$ref": "#/definitions/dto.LyricsPageDTO"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на первую, предыдущую, следующую и последнюю страницы"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Общее количество куплетов"
                             }
                         }
                     },
@@ -135,9 +222,65 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/search": {
+            "get": {
+                "description": "Полнотекстовый поиск по куплетам: слова и фразы в кавычках, результаты отсортированы по релевантности, совпадения выделены тегом \u003cb\u003e",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lyrics"
+                ],
+                "summary": "Поиск по тексту песен",
+                "operationId": "search-lyrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество песен на странице",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение для пагинации",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.SearchDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/song": {
             "post": {
-                "description": "Добавление песни с указаым названием и группой",
+                "description": "Добавление песни с указаым названием и группой. Дата релиза, ссылка и текст, переданные клиентом, имеют приоритет над данными внешнего API; недостающие загружаются в фоне. С enrich=false внешний API не вызывается",
                 "consumes": [
                     "application/json"
                 ],
@@ -158,11 +301,29 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/dto.CreateSongRequest"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Загружать недостающие данные из внешнего API, по умолчанию true",
+                        "name": "enrich",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повторный запрос с тем же ключом получает исходный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created",
+                        "description": "Песня сохранена со всеми данными",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateSongResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Недостающие данные загружаются в фоне",
                         "schema": {
                             "$ref": "#/definitions/dto.CreateSongResponse"
                         }
@@ -174,6 +335,113 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "409": {
+                        "description": "Песня уже существует, Location указывает на неё, или находится в корзине",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес существующей песни"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Ключ идемпотентности использован для другого запроса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/song/by-name": {
+            "put": {
+                "description": "Создаёт песню с указанными группой и названием, как POST /api/v1/song, или, если она уже есть, заменяет переданными значениями сохранённые дату релиза, ссылку и текст. Пустые значения не меняют сохранённые данные",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Создание или обновление песни по названию",
+                "operationId": "upsert-song",
+                "parameters": [
+                    {
+                        "description": "Song",
+                        "name": "song",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateSongRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Загружать недостающие данные новой песни из внешнего API, по умолчанию true",
+                        "name": "enrich",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Песня обновлена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpsertSongDTO"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес песни"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Песня создана со всеми данными",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpsertSongDTO"
+                        }
+                    },
+                    "202": {
+                        "description": "Песня создана, недостающие данные загружаются в фоне",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpsertSongDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Песня находится в корзине",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -185,6 +453,55 @@ const docTemplate = `{
             }
         },
         "/api/v1/song/{id}": {
+            "get": {
+                "description": "Получение песни со всеми куплетами",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Получение песни",
+                "operationId": "get-song",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.SongDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
             "put": {
                 "description": "Обновление полей песни и текста куплетов",
                 "consumes": [
@@ -231,6 +548,20 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Песня с такими группой и названием уже есть",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -241,7 +572,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Удалене песни по id",
+                "description": "Перемещает песню в корзину. Из корзины её можно восстановить, пока она не удалена окончательно по истечении срока хранения",
                 "consumes": [
                     "application/json"
                 ],
@@ -277,6 +608,13 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -286,28 +624,600 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.CreateSongRequest": {
-            "type": "object",
-            "required": [
-                "group",
-                "name"
-            ],
-            "properties": {
-                "group": {
-                    "type": "string"
-                },
-                "name": {
-                    "type": "string"
-                }
-            }
         },
-        "dto.CreateSongResponse": {
-            "type": "object",
-            "properties": {
-                "group": {
+        "/api/v1/song/{id}/enrichment": {
+            "get": {
+                "description": "Статус фоновой загрузки ссылки, даты релиза и текста песни из внешнего API: статус песни, состояние задачи, число попыток и последняя ошибка",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Статус загрузки данных песни",
+                "operationId": "get-enrichment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.EnrichmentDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/song/{id}/history": {
+            "get": {
+                "description": "Список ревизий песни от новых к старым: кто, когда и что изменил в полях и куплетах. Ревизии записываются при каждом изменении песни, включая загрузку данных из внешнего API",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "История изменений песни",
+                "operationId": "get-song-history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество ревизий",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение для пагинации",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.HistoryDTO"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на первую, предыдущую, следующую и последнюю страницы"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Общее количество ревизий"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/song/{id}/refresh": {
+            "post": {
+                "description": "Повторно запрашивает ссылку, дату релиза и текст песни во внешнем API, применяет отличия от сохранённых данных в одной транзакции и возвращает список изменений. Пустые значения из API не стирают сохранённые данные",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Обновление песни из внешнего API",
+                "operationId": "refresh-song",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RefreshDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Данные песни ещё загружаются в фоне",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "422": {
+                        "description": "Внешний API не знает эту песню",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/song/{id}/restore": {
+            "post": {
+                "description": "Возвращает удалённую песню вместе с куплетами и историей изменений",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Восстановление песни из корзины",
+                "operationId": "restore-song",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.SongDTO"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес песни"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Песни нет в корзине",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/song/{id}/revert/{rev}": {
+            "post": {
+                "description": "Отменяет ревизию и все более поздние, возвращая песню в состояние до неё. Откат записывается как новая ревизия, поэтому его тоже можно отменить",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Откат песни к ревизии",
+                "operationId": "revert-song",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер ревизии",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Автор изменения для истории, по умолчанию anonymous",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RefreshDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Песня с прежними группой и названием уже существует",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/song/{id}/verses": {
+            "post": {
+                "description": "Вставка куплета на указанную позицию или в конец песни, последующие куплеты сдвигаются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lyrics"
+                ],
+                "summary": "Добавление куплета",
+                "operationId": "add-verse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Verse",
+                        "name": "verse",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateVerseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LyricsDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/song/{id}/verses/order": {
+            "put": {
+                "description": "Новый порядок задается списком текущих номеров всех куплетов",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lyrics"
+                ],
+                "summary": "Изменение порядка куплетов",
+                "operationId": "reorder-verses",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Verse order",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ReorderVersesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/song/{id}/verses/{verse}": {
+            "delete": {
+                "description": "Удаление куплета по номеру, последующие куплеты перенумеровываются",
+                "tags": [
+                    "Lyrics"
+                ],
+                "summary": "Удаление куплета",
+                "operationId": "delete-verse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Verse number",
+                        "name": "verse",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/trash": {
+            "get": {
+                "description": "Список удалённых песен от недавно удалённых к давним. Песни хранятся в корзине до окончательного удаления по истечении срока хранения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Корзина",
+                "operationId": "get-trash",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Количество песен",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение для пагинации",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TrashDTO"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на первую, предыдущую, следующую и последнюю страницы"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Общее количество песен в корзине"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.CreateSongRequest": {
+            "type": "object",
+            "required": [
+                "group",
+                "name"
+            ],
+            "properties": {
+                "group": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "release_date": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CreateSongResponse": {
+            "type": "object",
+            "properties": {
+                "group": {
                     "type": "string"
                 },
                 "id": {
@@ -322,19 +1232,117 @@ const docTemplate = `{
                 "release_date": {
                     "type": "string"
                 },
+                "status": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CreateVerseRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "position": {
+                    "type": "integer"
+                },
                 "text": {
                     "type": "string"
                 }
             }
         },
+        "dto.EnrichmentDTO": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "job_status": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.FieldChangeDTO": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "new": {
+                    "type": "string"
+                },
+                "old": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.HistoryDTO": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "revisions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RevisionDTO"
+                    }
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "dto.LibraryDTO": {
             "type": "object",
             "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
                 "songs": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/dto.SongDTO"
                     }
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         },
@@ -349,9 +1357,163 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.LyricsPageDTO": {
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "type": "string"
+                },
+                "group": {
+                    "type": "string"
+                },
+                "has_more": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inserted_at": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "release_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "verses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.LyricsDTO"
+                    }
+                }
+            }
+        },
+        "dto.RefreshDTO": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "type": "boolean"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FieldChangeDTO"
+                    }
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "verses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.VerseChangeDTO"
+                    }
+                }
+            }
+        },
+        "dto.ReorderVersesRequest": {
+            "type": "object",
+            "required": [
+                "order"
+            ],
+            "properties": {
+                "order": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "dto.RevisionDTO": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FieldChangeDTO"
+                    }
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "verses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.VerseChangeDTO"
+                    }
+                }
+            }
+        },
+        "dto.SearchDTO": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.SearchResultDTO"
+                    }
+                }
+            }
+        },
+        "dto.SearchResultDTO": {
+            "type": "object",
+            "properties": {
+                "group": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rank": {
+                    "type": "number"
+                },
+                "release_date": {
+                    "type": "string"
+                },
+                "verses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.LyricsDTO"
+                    }
+                }
+            }
+        },
         "dto.SongDTO": {
             "type": "object",
             "properties": {
+                "deleted_at": {
+                    "type": "string"
+                },
                 "group": {
                     "type": "string"
                 },
@@ -370,6 +1532,9 @@ const docTemplate = `{
                 "release_date": {
                     "type": "string"
                 },
+                "status": {
+                    "type": "string"
+                },
                 "verses": {
                     "type": "array",
                     "items": {
@@ -378,6 +1543,63 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.TrashDTO": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "songs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.SongDTO"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UpsertSongDTO": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "$ref": "#/definitions/dto.RefreshDTO"
+                },
+                "created": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.VerseChangeDTO": {
+            "type": "object",
+            "properties": {
+                "change": {
+                    "type": "string"
+                },
+                "new": {
+                    "type": "string"
+                },
+                "old": {
+                    "type": "string"
+                },
+                "verse_number": {
+                    "type": "integer"
+                }
+            }
+        },
         "model.SongUpdate": {
             "type": "object",
             "properties": {
@@ -1,45 +1,75 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"songs_lib/config"
 	"songs_lib/internal/service"
-	"songs_lib/internal/storage/postgresql"
+	"songs_lib/internal/storage"
 	web "songs_lib/internal/web/api"
+	external "songs_lib/internal/web/external"
 	"songs_lib/pkg/logger"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
 type App struct {
-	log   *slog.Logger
-	port  int
-	fiber *fiber.App
-	DB    *postgresql.PostgresStorage
+	log       *slog.Logger
+	port      int
+	fiber     *fiber.App
+	enricher  *service.Enricher
+	refresher *service.Refresher
+	purger    *service.Purger
+	DB        storage.Storage
 }
 
-func NewApp(log *slog.Logger, httpServer config.HTTP, storage config.Storage, externalAPI string) (*App, error) {
-	psStorage, err := postgresql.NewPostgresStorage(log, storage.Path)
+func NewApp(
+	log *slog.Logger,
+	httpServer config.HTTP,
+	storageCfg config.Storage,
+	externalAPI config.ExternalAPI,
+	enrichment config.Enrichment,
+	refresh config.Refresh,
+	idempotency config.Idempotency,
+	trash config.Trash,
+) (*App, error) {
+	providers, err := external.NewProviders(log, externalAPI)
 	if err != nil {
-		log.Error("error creating storage: %v", logger.Err(err))
+		log.Error("error creating song info providers", logger.Err(err))
 		return nil, err
 	}
-	log.Debug("Storage setup successfully by path ", slog.String("path", storage.Path))
 
-	songService := service.NewSongService(log, psStorage)
-	songsHandlers := web.NewSongsHandlers(log, songService, externalAPI)
+	db, err := NewStorage(log, storageCfg)
+	if err != nil {
+		log.Error("error creating storage", logger.Err(err))
+		return nil, err
+	}
+	log.Debug("Storage setup successfully by path ", slog.String("path", storageCfg.Path))
+
+	songService := service.NewSongService(log, db, providers)
+	idempotencyService := service.NewIdempotencyService(log, db, idempotency)
+	songsHandlers := web.NewSongsHandlers(log, songService, idempotencyService)
+	// refreshing is meant to see the current upstream data, so only the
+	// enrichment of new songs is cached
+	enricher := service.NewEnricher(log, db, external.NewCache(providers, externalAPI), enrichment)
+	refresher := service.NewRefresher(log, db, providers, refresh)
+	purger := service.NewPurger(log, db, trash)
 
 	fiber := SetupFiber(httpServer)
 
 	web.SetupRoutes(fiber, songsHandlers)
 
 	return &App{
-		log:   log,
-		port:  httpServer.Port,
-		fiber: fiber,
-		DB:    psStorage,
+		log:       log,
+		port:      httpServer.Port,
+		fiber:     fiber,
+		enricher:  enricher,
+		refresher: refresher,
+		purger:    purger,
+		DB:        db,
 	}, nil
 }
 
@@ -57,11 +87,36 @@ func SetupFiber(http config.HTTP) *fiber.App {
 		AllowHeaders:     "Origin, Content-Type, Accept",
 		AllowCredentials: true,
 	}))
+	app.Use(timeoutContext(http.Timeout))
 
 	return app
 }
 
+// timeoutContext bounds the user context of every request, so storage queries
+// and external API calls are canceled once the HTTP timeout is exceeded.
+func timeoutContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
 func (a *App) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a.log.Info("Starting enrichment workers")
+	go a.enricher.Run(ctx)
+	go a.refresher.Run(ctx)
+	go a.purger.Run(ctx)
+
 	a.log.Info("Starting http server", slog.Int("port", a.port))
 
 	if err := a.fiber.Listen(fmt.Sprintf(":%d", a.port)); err != nil {
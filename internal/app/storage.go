@@ -0,0 +1,68 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"songs_lib/config"
+	"songs_lib/internal/storage"
+	"songs_lib/internal/storage/memory"
+	"songs_lib/internal/storage/postgresql"
+	"songs_lib/internal/storage/sqlite"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+func NewStorage(log *slog.Logger, cfg config.Storage) (storage.Storage, error) {
+	scheme, err := storageScheme(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scheme {
+	case "memory":
+		return memory.NewMemoryStorage(log), nil
+	case "sqlite":
+		return sqlite.NewSQLiteStorage(log, cfg.Path, !cfg.SkipMigrations)
+	case "postgres", "postgresql":
+		return postgresql.NewPostgresStorage(log, cfg.Path, !cfg.SkipMigrations)
+	default:
+		return nil, fmt.Errorf("unsupported storage scheme %q", scheme)
+	}
+}
+
+// NewMigrate returns a migrate instance for the configured storage backend.
+func NewMigrate(cfg config.Storage) (*migrate.Migrate, error) {
+	scheme, err := storageScheme(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scheme {
+	case "memory":
+		return nil, errors.New("in-memory storage has no migrations")
+	case "sqlite":
+		return sqlite.NewMigrate(cfg.Path)
+	case "postgres", "postgresql":
+		return postgresql.NewMigrate(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unsupported storage scheme %q", scheme)
+	}
+}
+
+// storageScheme picks the backend by the scheme of the storage path. A path
+// without one, like the lib/pq key=value DSN "host=db dbname=songs", is a
+// Postgres connection string.
+func storageScheme(cfg config.Storage) (string, error) {
+	if !strings.Contains(cfg.Path, "://") {
+		return "postgres", nil
+	}
+
+	u, err := url.Parse(cfg.Path)
+	if err != nil {
+		return "", fmt.Errorf("invalid storage path: %w", err)
+	}
+	return u.Scheme, nil
+}
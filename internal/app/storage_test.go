@@ -0,0 +1,22 @@
+package app
+
+import (
+	"songs_lib/config"
+	"testing"
+)
+
+func TestStorageScheme(t *testing.T) {
+	for path, want := range map[string]string{
+		"memory://":         "memory",
+		"sqlite://songs.db": "sqlite",
+		"postgres://postgres:postgres@db:5432/songs?sslmode=disable": "postgres",
+		"postgresql://db/songs":                                         "postgresql",
+		"host=db user=postgres dbname=songs sslmode=disable":            "postgres",
+		"host=db user=postgres password=se:cret dbname=songs port=5432": "postgres",
+	} {
+		got, err := storageScheme(config.Storage{Path: path})
+		if err != nil || got != want {
+			t.Errorf("storageScheme(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+}
@@ -5,18 +5,33 @@ import (
 	"time"
 )
 
+// CreateSongRequest may carry the details of the song. They take precedence
+// over the ones fetched from the external API.
 type CreateSongRequest struct {
-	Group string `json:"group" validate:"required"`
-	Name  string `json:"name" validate:"required"`
+	Group       string `json:"group" validate:"required"`
+	Name        string `json:"name" validate:"required"`
+	ReleaseDate string `json:"release_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Link        string `json:"link,omitempty" validate:"omitempty,url"`
+	Text        string `json:"text,omitempty"`
+}
+
+type CreateVerseRequest struct {
+	Text     string `json:"text" validate:"required"`
+	Position uint   `json:"position,omitempty"`
+}
+
+type ReorderVersesRequest struct {
+	Order []uint `json:"order" validate:"required,min=1"`
 }
 
 type CreateSongResponse struct {
-	ID          uint      `json:"id"`
-	Group       string    `json:"group"`
-	Name        string    `json:"name"`
-	ReleaseDate time.Time `json:"release_date"`
-	Link        string    `json:"link,omitempty"`
-	Text        string    `json:"text,omitempty"`
+	ID          uint       `json:"id"`
+	Group       string     `json:"group"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	ReleaseDate *time.Time `json:"release_date,omitempty"`
+	Link        string     `json:"link,omitempty"`
+	Text        string     `json:"text,omitempty"`
 }
 
 type LyricsDTO struct {
@@ -28,14 +43,110 @@ type SongDTO struct {
 	ID          uint        `json:"id,omitempty"`
 	Group       string      `json:"group,omitempty"`
 	Name        string      `json:"name,omitempty"`
-	ReleaseDate time.Time   `json:"release_date,omitempty"`
+	ReleaseDate *time.Time  `json:"release_date,omitempty"`
 	Link        string      `json:"link,omitempty"`
 	InsertedAt  string      `json:"inserted_at,omitempty"`
+	Status      string      `json:"status,omitempty"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"`
 	Lyrics      []LyricsDTO `json:"verses,omitempty"`
 }
 
+// PaginationDTO describes which part of a list a response contains. Total
+// counts every matching item, regardless of the page.
+type PaginationDTO struct {
+	Total   int  `json:"total"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasMore bool `json:"has_more"`
+}
+
 type LibraryDTO struct {
+	Songs      []SongDTO `json:"songs"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PaginationDTO
+}
+
+type LyricsPageDTO struct {
+	SongDTO
+	PaginationDTO
+}
+
+// EnrichmentDTO reports how far fetching the details of a song has got.
+type EnrichmentDTO struct {
+	SongID        uint       `json:"song_id"`
+	Status        string     `json:"status"`
+	JobStatus     string     `json:"job_status,omitempty"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// RefreshDTO reports what refreshing a song from the external API changed.
+type RefreshDTO struct {
+	SongID  uint             `json:"song_id"`
+	Changed bool             `json:"changed"`
+	Fields  []FieldChangeDTO `json:"fields"`
+	Verses  []VerseChangeDTO `json:"verses"`
+}
+
+// UpsertSongDTO reports whether upserting a song created it and, if it
+// already existed, what changed.
+type UpsertSongDTO struct {
+	ID      uint        `json:"id"`
+	Created bool        `json:"created"`
+	Status  string      `json:"status"`
+	Changes *RefreshDTO `json:"changes,omitempty"`
+}
+
+// RevisionDTO is one recorded change of a song.
+type RevisionDTO struct {
+	Revision  uint             `json:"revision"`
+	Actor     string           `json:"actor"`
+	CreatedAt time.Time        `json:"created_at"`
+	Fields    []FieldChangeDTO `json:"fields"`
+	Verses    []VerseChangeDTO `json:"verses"`
+}
+
+// TrashDTO lists the deleted songs that can still be restored, most
+// recently deleted first.
+type TrashDTO struct {
 	Songs []SongDTO `json:"songs"`
+	PaginationDTO
+}
+
+// HistoryDTO lists the revisions of a song, newest first.
+type HistoryDTO struct {
+	SongID    uint          `json:"song_id"`
+	Revisions []RevisionDTO `json:"revisions"`
+	PaginationDTO
+}
+
+type FieldChangeDTO struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type VerseChangeDTO struct {
+	VerseNumber uint   `json:"verse_number"`
+	Change      string `json:"change"`
+	Old         string `json:"old,omitempty"`
+	New         string `json:"new,omitempty"`
+}
+
+type SearchResultDTO struct {
+	ID          uint        `json:"id"`
+	Group       string      `json:"group"`
+	Name        string      `json:"name"`
+	ReleaseDate *time.Time  `json:"release_date,omitempty"`
+	Link        string      `json:"link,omitempty"`
+	Rank        float64     `json:"rank"`
+	Verses      []LyricsDTO `json:"verses"`
+}
+
+type SearchDTO struct {
+	Results []SearchResultDTO `json:"results"`
 }
 
 func LyricsToDTO(lyrics model.Lyrics) LyricsDTO {
@@ -55,9 +166,100 @@ func SongToDTO(song model.Song, lyrics []model.Lyrics) SongDTO {
 		ID:          song.ID,
 		Group:       song.Group,
 		Name:        song.Name,
-		ReleaseDate: song.ReleaseDate,
+		ReleaseDate: knownDate(song.ReleaseDate),
 		Link:        song.Link,
 		InsertedAt:  song.InsertedAt.Format("2006-01-02 15:04:05"),
+		Status:      string(song.Status),
+		DeletedAt:   song.DeletedAt,
 		Lyrics:      lyricsDTO,
 	}
 }
+
+// knownDate returns nil for the zero release date of a song whose details
+// are not fetched yet, so it is left out of responses.
+func knownDate(date time.Time) *time.Time {
+	if date.IsZero() {
+		return nil
+	}
+	return &date
+}
+
+func SearchResultToDTO(result model.SearchResult) SearchResultDTO {
+	verses := make([]LyricsDTO, 0, len(result.Verses))
+	for _, verse := range result.Verses {
+		verses = append(verses, LyricsToDTO(verse))
+	}
+
+	return SearchResultDTO{
+		ID:          result.Song.ID,
+		Group:       result.Song.Group,
+		Name:        result.Song.Name,
+		ReleaseDate: knownDate(result.Song.ReleaseDate),
+		Link:        result.Song.Link,
+		Rank:        result.Rank,
+		Verses:      verses,
+	}
+}
+
+func EnrichmentToDTO(song model.Song, job *model.EnrichmentJob) EnrichmentDTO {
+	enrichment := EnrichmentDTO{
+		SongID: song.ID,
+		Status: string(song.Status),
+	}
+	if job == nil {
+		return enrichment
+	}
+
+	enrichment.JobStatus = string(job.Status)
+	enrichment.Attempts = job.Attempts
+	enrichment.LastError = job.LastError
+	enrichment.UpdatedAt = &job.UpdatedAt
+	if job.Status == model.EnrichmentJobPending {
+		enrichment.NextAttemptAt = &job.RunAt
+	}
+	return enrichment
+}
+
+func RefreshToDTO(diff model.SongDiff) RefreshDTO {
+	return RefreshDTO{
+		SongID:  diff.Song.ID,
+		Changed: !diff.Empty(),
+		Fields:  fieldChangesToDTO(diff.Fields),
+		Verses:  verseChangesToDTO(diff.Verses),
+	}
+}
+
+func RevisionToDTO(revision model.SongRevision) RevisionDTO {
+	return RevisionDTO{
+		Revision:  revision.Number,
+		Actor:     revision.Actor,
+		CreatedAt: revision.CreatedAt,
+		Fields:    fieldChangesToDTO(revision.Fields),
+		Verses:    verseChangesToDTO(revision.Verses),
+	}
+}
+
+func fieldChangesToDTO(changes []model.FieldChange) []FieldChangeDTO {
+	fields := make([]FieldChangeDTO, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, FieldChangeDTO{
+			Field: change.Field,
+			Old:   change.Old,
+			New:   change.New,
+		})
+	}
+	return fields
+}
+
+func verseChangesToDTO(changes []model.VerseChange) []VerseChangeDTO {
+	verses := make([]VerseChangeDTO, 0, len(changes))
+	for _, change := range changes {
+		verses = append(verses, VerseChangeDTO{
+			VerseNumber: change.VerseNumber,
+			Change:      string(change.Kind),
+			Old:         change.Old,
+			New:         change.New,
+		})
+	}
+	return verses
+}
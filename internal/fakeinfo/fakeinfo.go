@@ -0,0 +1,180 @@
+// Package fakeinfo is a stub of the external music info API. It answers
+// GET /info?group=&song= from fixtures, with optional latency and injected
+// errors, for local development and tests.
+package fakeinfo
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	external "songs_lib/internal/web/external"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures/*.json
+var defaultFixtures embed.FS
+
+// Fixture is one song the server knows, in the format of the seed command.
+// A non-zero Status makes the server answer with that status instead.
+type Fixture struct {
+	Group  string `json:"group"`
+	Song   string `json:"song"`
+	Status int    `json:"status,omitempty"`
+	external.FetchData
+}
+
+// Options control how the server misbehaves. Every request waits Latency plus
+// a random part of Jitter, then fails with ErrorStatus with probability
+// ErrorRate.
+type Options struct {
+	Latency     time.Duration
+	Jitter      time.Duration
+	ErrorRate   float64
+	ErrorStatus int
+}
+
+// DefaultFixtures returns the fixtures bundled with the package.
+func DefaultFixtures() ([]Fixture, error) {
+	return loadFixtures(defaultFixtures, "fixtures")
+}
+
+// LoadFixtures reads fixtures from a JSON file or from every JSON file of a
+// directory.
+func LoadFixtures(path string) ([]Fixture, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	if info.IsDir() {
+		return loadFixtures(os.DirFS(path), ".")
+	}
+	return loadFixtures(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
+
+func loadFixtures(fsys fs.FS, root string) ([]Fixture, error) {
+	var fixtures []Fixture
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		var file []Fixture
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to decode fixtures %s: %w", path, err)
+		}
+		fixtures = append(fixtures, file...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fixtures, nil
+}
+
+// Server serves the /info endpoint of the external API.
+type Server struct {
+	fixtures map[fixtureKey]Fixture
+	opts     Options
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+type fixtureKey struct {
+	group string
+	song  string
+}
+
+func newFixtureKey(group, song string) fixtureKey {
+	return fixtureKey{
+		group: strings.ToLower(strings.TrimSpace(group)),
+		song:  strings.ToLower(strings.TrimSpace(song)),
+	}
+}
+
+func NewServer(fixtures []Fixture, opts Options) *Server {
+	if opts.ErrorStatus == 0 {
+		opts.ErrorStatus = http.StatusInternalServerError
+	}
+
+	s := &Server{
+		fixtures: make(map[fixtureKey]Fixture, len(fixtures)),
+		opts:     opts,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, fixture := range fixtures {
+		s.fixtures[newFixtureKey(fixture.Group, fixture.Song)] = fixture
+	}
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/info" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	delay, fail := s.misbehave()
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	if fail {
+		w.WriteHeader(s.opts.ErrorStatus)
+		return
+	}
+
+	group, song := r.URL.Query().Get("group"), r.URL.Query().Get("song")
+	if group == "" || song == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fixture, ok := s.fixtures[newFixtureKey(group, song)]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if fixture.Status != 0 {
+		w.WriteHeader(fixture.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fixture.FetchData)
+}
+
+// misbehave returns how long to delay the request and whether to fail it.
+func (s *Server) misbehave() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delay := s.opts.Latency
+	if s.opts.Jitter > 0 {
+		delay += time.Duration(s.rand.Int63n(int64(s.opts.Jitter)))
+	}
+	return delay, s.opts.ErrorRate > 0 && s.rand.Float64() < s.opts.ErrorRate
+}
@@ -0,0 +1,121 @@
+package fakeinfo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"songs_lib/config"
+	external "songs_lib/internal/web/external"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, fixtures []Fixture, opts Options) *external.Client {
+	t.Helper()
+	server := httptest.NewServer(NewServer(fixtures, opts))
+	t.Cleanup(server.Close)
+
+	return external.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), config.ExternalAPI{
+		URL:            server.URL,
+		Timeout:        time.Second,
+		RetryBaseDelay: time.Millisecond,
+	})
+}
+
+func TestServerFixtures(t *testing.T) {
+	fixtures, err := DefaultFixtures()
+	if err != nil {
+		t.Fatalf("DefaultFixtures: %v", err)
+	}
+	client := newTestClient(t, fixtures, Options{})
+
+	details, err := client.FetchSong(context.Background(), "muse", "Supermassive Black Hole")
+	if err != nil {
+		t.Fatalf("FetchSong: %v", err)
+	}
+	if details.ReleaseDate != "16.07.2006" || details.Link == "" || details.Text == "" {
+		t.Errorf("FetchSong = %+v", details)
+	}
+
+	tests := []struct {
+		song   string
+		status int
+	}{
+		{"Unknown", http.StatusNotFound},
+		{"Broken Upstream", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		_, err := client.FetchSong(context.Background(), "Muse", tt.song)
+		var statusErr *external.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+			t.Errorf("FetchSong(%q) error = %v, want status %d", tt.song, err, tt.status)
+		}
+	}
+}
+
+func TestServerErrorInjection(t *testing.T) {
+	fixtures := []Fixture{{Group: "Muse", Song: "Uprising", FetchData: external.FetchData{Link: "https://example.com"}}}
+	client := newTestClient(t, fixtures, Options{ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable})
+
+	_, err := client.FetchSong(context.Background(), "Muse", "Uprising")
+	var statusErr *external.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("FetchSong error = %v, want status 503", err)
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	fixtures := []Fixture{{Group: "Muse", Song: "Uprising", FetchData: external.FetchData{Link: "https://example.com"}}}
+	client := newTestClient(t, fixtures, Options{Latency: 50 * time.Millisecond})
+
+	start := time.Now()
+	if _, err := client.FetchSong(context.Background(), "Muse", "Uprising"); err != nil {
+		t.Fatalf("FetchSong: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("FetchSong took %v, want at least the latency", elapsed)
+	}
+}
+
+func TestLoadFixturesDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"muse.json":   `[{"group": "Muse", "song": "Uprising", "link": "https://example.com"}]`,
+		"queen.json":  `[{"group": "Queen", "song": "Bohemian Rhapsody", "status": 404}]`,
+		"readme.txt":  `not fixtures`,
+		"broken.json": ``,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := LoadFixtures(dir); err == nil {
+		t.Error("LoadFixtures succeeded with an invalid file")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "broken.json")); err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("LoadFixtures: %v", err)
+	}
+	if len(fixtures) != 2 {
+		t.Errorf("LoadFixtures = %+v, want 2 fixtures", fixtures)
+	}
+
+	fixtures, err = LoadFixtures(filepath.Join(dir, "muse.json"))
+	if err != nil {
+		t.Fatalf("LoadFixtures(file): %v", err)
+	}
+	if len(fixtures) != 1 || fixtures[0].Link != "https://example.com" {
+		t.Errorf("LoadFixtures(file) = %+v", fixtures)
+	}
+}
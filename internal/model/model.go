@@ -9,18 +9,217 @@ type Lyrics struct {
 }
 
 type Song struct {
-	ID          uint      `json:"id"`
-	Group       string    `json:"group"`
-	Name        string    `json:"name"`
-	ReleaseDate time.Time `json:"release_date"`
-	Link        string    `json:"link"`
-	InsertedAt  time.Time `json:"inserted_at"`
+	ID          uint       `json:"id"`
+	Group       string     `json:"group"`
+	Name        string     `json:"name"`
+	ReleaseDate time.Time  `json:"release_date"`
+	Link        string     `json:"link"`
+	InsertedAt  time.Time  `json:"inserted_at"`
+	Status      SongStatus `json:"status"`
+	// ClientFields are the details the client set, which refreshing from the
+	// external API leaves alone.
+	ClientFields SongFields `json:"-"`
+	// DeletedAt is set while the song is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// SongFields is a set of the details of a song the external API provides.
+type SongFields uint8
+
+const (
+	FieldLink SongFields = 1 << iota
+	FieldReleaseDate
+	FieldVerses
+
+	AllSongFields = FieldLink | FieldReleaseDate | FieldVerses
+)
+
+// Has reports whether every field of fields is in the set.
+func (f SongFields) Has(fields SongFields) bool {
+	return f&fields == fields
+}
+
+type SongStatus string
+
+const (
+	SongStatusReady             SongStatus = "ready"
+	SongStatusPendingEnrichment SongStatus = "pending_enrichment"
+	SongStatusEnrichmentFailed  SongStatus = "enrichment_failed"
+)
+
+type EnrichmentJobStatus string
+
+const (
+	EnrichmentJobPending EnrichmentJobStatus = "pending"
+	EnrichmentJobRunning EnrichmentJobStatus = "running"
+	EnrichmentJobDone    EnrichmentJobStatus = "done"
+	EnrichmentJobFailed  EnrichmentJobStatus = "failed"
+)
+
+// EnrichmentJob fetches the details of a song from the external API. A
+// pending or running job is picked up again once RunAt has passed, so the
+// job of a crashed worker is retried.
+type EnrichmentJob struct {
+	ID        uint
+	SongID    uint
+	Status    EnrichmentJobStatus
+	Attempts  int
+	LastError string
+	RunAt     time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IdempotencyRecord is a request made with an Idempotency-Key and, once it
+// has finished, its response. StatusCode is zero while it is in progress.
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Location    string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// SongEnrichment holds the details fetched for a song. Only the fields the
+// song is missing are filled in.
+type SongEnrichment struct {
+	Link        string
+	ReleaseDate time.Time
+	Verses      []string
+}
+
+// Fields returns the set of details that are not empty.
+func (e SongEnrichment) Fields() SongFields {
+	var fields SongFields
+	if e.Link != "" {
+		fields |= FieldLink
+	}
+	if !e.ReleaseDate.IsZero() {
+		fields |= FieldReleaseDate
+	}
+	if len(e.Verses) > 0 {
+		fields |= FieldVerses
+	}
+	return fields
+}
+
+// SongFilter selects songs of the library. Zero fields don't filter.
+type SongFilter struct {
+	// Group is matched as a case-insensitive substring unless GroupExact is set.
+	Group      string
+	GroupExact bool
+	// Name is matched as a case-insensitive substring.
+	Name string
+	IDs  []uint
+
+	ReleaseDate *time.Time
+	// ReleaseDateFrom and ReleaseDateTo are inclusive.
+	ReleaseDateFrom *time.Time
+	ReleaseDateTo   *time.Time
+	// InsertedAfter and InsertedBefore are exclusive.
+	InsertedAfter  *time.Time
+	InsertedBefore *time.Time
+
+	HasLink   *bool
+	HasLyrics *bool
+}
+
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeModified ChangeKind = "changed"
+	ChangeRemoved  ChangeKind = "removed"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// VerseChange describes one verse; Old is empty for added verses and New for
+// removed ones.
+type VerseChange struct {
+	VerseNumber uint       `json:"verse_number"`
+	Kind        ChangeKind `json:"change"`
+	Old         string     `json:"old,omitempty"`
+	New         string     `json:"new,omitempty"`
+}
+
+// SongDiff is the result of refreshing a song: the song with the changes
+// applied and what changed.
+type SongDiff struct {
+	Song   Song
+	Fields []FieldChange
+	Verses []VerseChange
+}
+
+func (d SongDiff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Verses) == 0
+}
+
+// SongRevision is one recorded change of a song. Revisions of each song are
+// numbered from 1.
+type SongRevision struct {
+	SongID    uint
+	Number    uint
+	Actor     string
+	CreatedAt time.Time
+	Fields    []FieldChange
+	Verses    []VerseChange
+}
+
+type SortField string
+
+const (
+	SortByReleaseDate SortField = "release_date"
+	SortByInsertedAt  SortField = "inserted_at"
+	SortByName        SortField = "name"
+	SortByGroup       SortField = "group"
+)
+
+// SortKey orders the library by one field. Songs are always ordered by ID
+// after all keys, so the order is stable.
+type SortKey struct {
+	Field SortField
+	Desc  bool
+}
+
+// DefaultSongSort is used when no sort keys are given.
+var DefaultSongSort = []SortKey{{Field: SortByReleaseDate}}
+
+// SongCursor is the position of the last song of a library page: the values
+// of every sortable field and the ID.
+type SongCursor struct {
+	ReleaseDate time.Time
+	InsertedAt  time.Time
+	Name        string
+	Group       string
+	ID          uint
+}
+
+func CursorFromSong(song Song) SongCursor {
+	return SongCursor{
+		ReleaseDate: song.ReleaseDate,
+		InsertedAt:  song.InsertedAt,
+		Name:        song.Name,
+		Group:       song.Group,
+		ID:          song.ID,
+	}
 }
 
 type SongUpdate struct {
 	Group       string          `json:"group,omitempty"`
 	Name        string          `json:"name,omitempty"`
-	ReleaseDate string          `json:"release_date,omitempty"`
+	ReleaseDate string          `json:"release_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
 	Link        string          `json:"link,omitempty"`
 	Verses      map[uint]string `json:"verses,omitempty"`
 }
+
+type SearchResult struct {
+	Song   Song
+	Rank   float64
+	Verses []Lyrics
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"songs_lib/internal/model"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorToken keeps the sort the cursor was issued for, so it can't be
+// reused with a different order.
+type cursorToken struct {
+	Sort        string `json:"s,omitempty"`
+	ReleaseDate string `json:"d"`
+	InsertedAt  string `json:"t,omitempty"`
+	Name        string `json:"n,omitempty"`
+	Group       string `json:"g,omitempty"`
+	ID          uint   `json:"id"`
+}
+
+// encodeCursor returns an opaque token pointing right after song.
+func encodeCursor(song model.Song, sort []model.SortKey) string {
+	token := cursorToken{
+		Sort:        formatSort(sort),
+		ReleaseDate: song.ReleaseDate.Format("2006-01-02"),
+		ID:          song.ID,
+	}
+	// only the sorted fields are needed to resume
+	for _, key := range sort {
+		switch key.Field {
+		case model.SortByInsertedAt:
+			token.InsertedAt = song.InsertedAt.UTC().Format(time.RFC3339Nano)
+		case model.SortByName:
+			token.Name = song.Name
+		case model.SortByGroup:
+			token.Group = song.Group
+		}
+	}
+
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string, sort []model.SortKey) (*model.SongCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if token.Sort != formatSort(sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	releaseDate, err := time.Parse("2006-01-02", token.ReleaseDate)
+	if err != nil || token.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	after := &model.SongCursor{
+		ReleaseDate: releaseDate,
+		Name:        token.Name,
+		Group:       token.Group,
+		ID:          token.ID,
+	}
+	if token.InsertedAt != "" {
+		if after.InsertedAt, err = time.Parse(time.RFC3339Nano, token.InsertedAt); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return after, nil
+}
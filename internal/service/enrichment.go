@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"songs_lib/config"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	external "songs_lib/internal/web/external"
+	"songs_lib/pkg/logger"
+	"sync"
+	"time"
+)
+
+// SongFetcher looks up the details of a song in an external source.
+type SongFetcher interface {
+	FetchSong(ctx context.Context, group, song string) (*external.FetchData, error)
+}
+
+// errPermanent marks enrichment failures that retrying won't fix.
+var errPermanent = errors.New("permanent enrichment failure")
+
+// Enricher runs a pool of workers that take enrichment jobs from storage and
+// fill in songs with details from the external API.
+type Enricher struct {
+	log          *slog.Logger
+	s            storage.Storage
+	fetcher      SongFetcher
+	workers      int
+	pollInterval time.Duration
+	maxAttempts  int
+	retryDelay   time.Duration
+	lease        time.Duration
+}
+
+func NewEnricher(log *slog.Logger, s storage.Storage, fetcher SongFetcher, cfg config.Enrichment) *Enricher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = 30 * time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 2 * time.Minute
+	}
+
+	return &Enricher{
+		log:          log,
+		s:            s,
+		fetcher:      fetcher,
+		workers:      cfg.Workers,
+		pollInterval: cfg.PollInterval,
+		maxAttempts:  cfg.MaxAttempts,
+		retryDelay:   cfg.RetryDelay,
+		lease:        cfg.Lease,
+	}
+}
+
+// Run starts the workers and blocks until ctx is canceled and they stopped.
+// A job interrupted by cancellation is picked up again once its lease ends.
+func (e *Enricher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < e.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (e *Enricher) work(ctx context.Context) {
+	for {
+		found, err := e.ProcessNext(ctx)
+		if err != nil && ctx.Err() == nil {
+			e.log.Error("Failed to process enrichment job", logger.Err(err))
+		}
+		if found && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.pollInterval):
+		}
+	}
+}
+
+// ProcessNext runs the earliest due enrichment job, if any, and reports
+// whether there was one.
+func (e *Enricher) ProcessNext(ctx context.Context) (bool, error) {
+	job, err := e.s.ClaimEnrichmentJob(ctx, time.Now(), e.lease)
+	if errors.Is(err, storage.ErrEnrichmentJobNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	log := e.log.With(slog.Int("song_id", int(job.SongID)), slog.Int("attempt", job.Attempts))
+
+	details, err := e.fetch(ctx, job.SongID)
+	if err == nil {
+		return true, e.s.CompleteEnrichmentJob(ctx, job.ID, *details)
+	}
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+
+	if errors.Is(err, errPermanent) || job.Attempts >= e.maxAttempts {
+		log.Warn("Song enrichment failed", logger.Err(err))
+		return true, e.s.FailEnrichmentJob(ctx, job.ID, err.Error())
+	}
+
+	delay := e.retryDelay << min(job.Attempts-1, 16)
+	log.Info("Song enrichment will be retried", slog.Duration("delay", delay), logger.Err(err))
+	return true, e.s.RetryEnrichmentJob(ctx, job.ID, err.Error(), time.Now().Add(delay))
+}
+
+func (e *Enricher) fetch(ctx context.Context, songID uint) (*model.SongEnrichment, error) {
+	song, err := e.s.GetSong(ctx, songID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := e.fetcher.FetchSong(ctx, song.Group, song.Name)
+	if errors.Is(err, external.ErrPartialData) {
+		// enrichment only fills in missing details, so what the other
+		// providers had is used; a refresh catches up later
+		err = nil
+	}
+	if err != nil {
+		var statusErr *external.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode < http.StatusInternalServerError {
+			return nil, fmt.Errorf("%w: %w", errPermanent, err)
+		}
+		return nil, err
+	}
+
+	return parseFetchData(data)
+}
+
+// parseFetchData converts an external API response into song details.
+func parseFetchData(data *external.FetchData) (*model.SongEnrichment, error) {
+	details := &model.SongEnrichment{Link: data.Link}
+
+	if data.ReleaseDate != "" {
+		releaseDate, err := time.Parse("02.01.2006", data.ReleaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid release date %q", errPermanent, data.ReleaseDate)
+		}
+		details.ReleaseDate = releaseDate
+	}
+
+	if data.Text != "" {
+		details.Verses = splitTextIntoVerses(data.Text)
+	}
+
+	return details, nil
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"songs_lib/config"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage/memory"
+	external "songs_lib/internal/web/external"
+	"testing"
+	"time"
+)
+
+type fakeFetcher struct {
+	data  *external.FetchData
+	err   error
+	calls int
+}
+
+func (f *fakeFetcher) FetchSong(ctx context.Context, group, song string) (*external.FetchData, error) {
+	f.calls++
+	return f.data, f.err
+}
+
+func newTestEnricher(t *testing.T, fetcher SongFetcher) (*Enricher, *SongService) {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := memory.NewMemoryStorage(log)
+	enricher := NewEnricher(log, s, fetcher, config.Enrichment{
+		MaxAttempts: 2,
+		RetryDelay:  time.Nanosecond,
+	})
+	return enricher, NewSongService(log, s, fetcher)
+}
+
+func processNext(t *testing.T, e *Enricher) {
+	t.Helper()
+	found, err := e.ProcessNext(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessNext: %v", err)
+	}
+	if !found {
+		t.Fatal("ProcessNext found no job")
+	}
+}
+
+func TestEnricherCompletes(t *testing.T) {
+	enricher, songs := newTestEnricher(t, &fakeFetcher{data: &external.FetchData{
+		ReleaseDate: "07.09.2009",
+		Link:        "https://example.com",
+		Text:        "one\n\ntwo",
+	}})
+
+	id, _, err := songs.AddSongForEnrichment(context.Background(), "Muse", "Uprising", "", time.Time{}, "")
+	if err != nil {
+		t.Fatalf("AddSongForEnrichment: %v", err)
+	}
+	processNext(t, enricher)
+
+	enrichment, err := songs.GetEnrichment(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetEnrichment: %v", err)
+	}
+	if enrichment.Status != string(model.SongStatusReady) || enrichment.JobStatus != string(model.EnrichmentJobDone) {
+		t.Errorf("enrichment = %+v", enrichment)
+	}
+
+	lyrics, err := songs.GetLyrics(context.Background(), id, "10", "0")
+	if err != nil {
+		t.Fatalf("GetLyrics: %v", err)
+	}
+	if len(lyrics.Lyrics) != 2 || lyrics.Lyrics[1].Text != "two" {
+		t.Errorf("lyrics = %+v", lyrics.Lyrics)
+	}
+
+	if found, err := enricher.ProcessNext(context.Background()); found || err != nil {
+		t.Errorf("ProcessNext = %v, %v, want no job left", found, err)
+	}
+}
+
+func TestEnricherUsesPartialData(t *testing.T) {
+	enricher, songs := newTestEnricher(t, &fakeFetcher{
+		data: &external.FetchData{Link: "https://fallback"},
+		err:  fmt.Errorf("%w: primary: %w", external.ErrPartialData, external.ErrCircuitOpen),
+	})
+
+	id, _, err := songs.AddSongForEnrichment(context.Background(), "Muse", "Uprising", "", time.Time{}, "")
+	if err != nil {
+		t.Fatalf("AddSongForEnrichment: %v", err)
+	}
+	processNext(t, enricher)
+
+	song, err := songs.GetSong(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != string(model.SongStatusReady) || song.Link != "https://fallback" {
+		t.Errorf("song = %+v, want it ready with the fallback link", song)
+	}
+}
+
+func TestEnricherRetriesThenFails(t *testing.T) {
+	enricher, songs := newTestEnricher(t, &fakeFetcher{
+		err: &external.StatusError{StatusCode: http.StatusBadGateway},
+	})
+
+	id, _, err := songs.AddSongForEnrichment(context.Background(), "Muse", "Uprising", "", time.Time{}, "")
+	if err != nil {
+		t.Fatalf("AddSongForEnrichment: %v", err)
+	}
+
+	processNext(t, enricher)
+	enrichment, err := songs.GetEnrichment(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetEnrichment: %v", err)
+	}
+	if enrichment.Status != string(model.SongStatusPendingEnrichment) ||
+		enrichment.JobStatus != string(model.EnrichmentJobPending) ||
+		enrichment.Attempts != 1 || enrichment.LastError == "" || enrichment.NextAttemptAt == nil {
+		t.Errorf("after first attempt enrichment = %+v", enrichment)
+	}
+
+	time.Sleep(time.Millisecond)
+	processNext(t, enricher)
+	enrichment, err = songs.GetEnrichment(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetEnrichment: %v", err)
+	}
+	if enrichment.Status != string(model.SongStatusEnrichmentFailed) ||
+		enrichment.JobStatus != string(model.EnrichmentJobFailed) || enrichment.Attempts != 2 {
+		t.Errorf("after last attempt enrichment = %+v", enrichment)
+	}
+}
+
+func TestEnricherDoesNotRetryUnknownSong(t *testing.T) {
+	enricher, songs := newTestEnricher(t, &fakeFetcher{
+		err: &external.StatusError{StatusCode: http.StatusNotFound},
+	})
+
+	id, _, err := songs.AddSongForEnrichment(context.Background(), "Muse", "Unknown", "", time.Time{}, "")
+	if err != nil {
+		t.Fatalf("AddSongForEnrichment: %v", err)
+	}
+	processNext(t, enricher)
+
+	enrichment, err := songs.GetEnrichment(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetEnrichment: %v", err)
+	}
+	if enrichment.Status != string(model.SongStatusEnrichmentFailed) || enrichment.Attempts != 1 {
+		t.Errorf("enrichment = %+v", enrichment)
+	}
+}
+
+func TestEnricherKeepsClientDetails(t *testing.T) {
+	enricher, songs := newTestEnricher(t, &fakeFetcher{data: &external.FetchData{
+		ReleaseDate: "07.09.2009",
+		Link:        "https://example.com",
+		Text:        "one\n\ntwo",
+	}})
+
+	id, status, err := songs.AddSongForEnrichment(context.Background(), "Muse", "Uprising", "https://client.example.com", time.Time{}, "client verse")
+	if err != nil {
+		t.Fatalf("AddSongForEnrichment: %v", err)
+	}
+	if status != model.SongStatusPendingEnrichment {
+		t.Errorf("status = %q, want %q", status, model.SongStatusPendingEnrichment)
+	}
+	processNext(t, enricher)
+
+	library, err := songs.GetLibrary(context.Background(), model.SongFilter{IDs: []uint{id}}, "", "", "10", "0", true)
+	if err != nil {
+		t.Fatalf("GetLibrary: %v", err)
+	}
+	song := library.Songs[0]
+	if song.Link != "https://client.example.com" || song.ReleaseDate.Format("2006-01-02") != "2009-09-07" {
+		t.Errorf("song = %+v, want the client link and the fetched release date", song)
+	}
+	if len(song.Lyrics) != 1 || song.Lyrics[0].Text != "client verse" {
+		t.Errorf("verses = %+v, want the client text", song.Lyrics)
+	}
+}
+
+func TestAddSongWithAllDetailsSkipsEnrichment(t *testing.T) {
+	enricher, songs := newTestEnricher(t, &fakeFetcher{err: errors.New("must not be called")})
+
+	releaseDate := time.Date(2009, 9, 7, 0, 0, 0, 0, time.UTC)
+	_, status, err := songs.AddSongForEnrichment(context.Background(), "Muse", "Uprising", "https://example.com", releaseDate, "verse")
+	if err != nil {
+		t.Fatalf("AddSongForEnrichment: %v", err)
+	}
+	if status != model.SongStatusReady {
+		t.Errorf("status = %q, want %q", status, model.SongStatusReady)
+	}
+	if found, err := enricher.ProcessNext(context.Background()); found || err != nil {
+		t.Errorf("ProcessNext = %v, %v, want no job", found, err)
+	}
+}
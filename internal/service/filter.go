@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"songs_lib/internal/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+const (
+	GroupMatchFuzzy = "fuzzy"
+	GroupMatchExact = "exact"
+)
+
+// ParseSongFilter builds a library filter from query parameters. Empty
+// parameters are ignored.
+func ParseSongFilter(params map[string]string) (model.SongFilter, error) {
+	filter := model.SongFilter{
+		Group: params["group"],
+		Name:  params["name"],
+	}
+
+	switch params["group_match"] {
+	case "", GroupMatchFuzzy:
+	case GroupMatchExact:
+		filter.GroupExact = true
+	default:
+		return filter, fmt.Errorf("%w: group_match must be %q or %q", ErrInvalidFilter, GroupMatchFuzzy, GroupMatchExact)
+	}
+
+	if ids := params["id"]; ids != "" {
+		for _, value := range strings.Split(ids, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 0)
+			if err != nil || id == 0 {
+				return filter, fmt.Errorf("%w: invalid id %q", ErrInvalidFilter, value)
+			}
+			filter.IDs = append(filter.IDs, uint(id))
+		}
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+		parse  func(string) (time.Time, error)
+	}{
+		{"release_date", &filter.ReleaseDate, parseDate},
+		{"release_date_from", &filter.ReleaseDateFrom, parseDate},
+		{"release_date_to", &filter.ReleaseDateTo, parseDate},
+		{"inserted_after", &filter.InsertedAfter, parseTimestamp},
+		{"inserted_before", &filter.InsertedBefore, parseTimestamp},
+	} {
+		value := params[param.name]
+		if value == "" {
+			continue
+		}
+		t, err := param.parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("%w: invalid %s %q", ErrInvalidFilter, param.name, value)
+		}
+		*param.target = &t
+	}
+
+	var err error
+	if filter.HasLink, err = parseOptionalBool(params, "has_link"); err != nil {
+		return filter, err
+	}
+	if filter.HasLyrics, err = parseOptionalBool(params, "has_lyrics"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	return time.Parse("2006-01-02", value)
+}
+
+// parseTimestamp accepts RFC 3339 timestamps and plain dates.
+func parseTimestamp(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return parseDate(value)
+}
+
+func parseOptionalBool(params map[string]string, name string) (*bool, error) {
+	value := params[name]
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s %q", ErrInvalidFilter, name, value)
+	}
+	return &b, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"songs_lib/internal/dto"
+	"songs_lib/pkg/logger"
+)
+
+// GetHistory returns a page of the revisions of a song, newest first.
+func (s *SongService) GetHistory(ctx context.Context, songID uint, limit, offset string) (*dto.HistoryDTO, error) {
+	limitInt, offsetInt := getLimitAndOffset(limit, offset)
+
+	revisions, err := s.s.GetSongRevisions(ctx, songID, limitInt, offsetInt)
+	if err != nil {
+		s.log.Error("Failed to get song revisions", logger.Err(err))
+		return nil, err
+	}
+
+	total, err := s.s.CountSongRevisions(ctx, songID)
+	if err != nil {
+		s.log.Error("Failed to count song revisions", logger.Err(err))
+		return nil, err
+	}
+
+	history := &dto.HistoryDTO{
+		SongID:    songID,
+		Revisions: make([]dto.RevisionDTO, 0, len(revisions)),
+		PaginationDTO: dto.PaginationDTO{
+			Total:   total,
+			Limit:   limitInt,
+			Offset:  offsetInt,
+			HasMore: limitInt > 0 && offsetInt+len(revisions) < total,
+		},
+	}
+	for _, revision := range revisions {
+		history.Revisions = append(history.Revisions, dto.RevisionToDTO(revision))
+	}
+	return history, nil
+}
+
+// RevertSong undoes a revision of a song and every later one. The revert is
+// recorded as a new revision, so it can be undone too.
+func (s *SongService) RevertSong(ctx context.Context, songID uint, revision uint) (*dto.RefreshDTO, error) {
+	diff, err := s.s.RevertSong(ctx, songID, revision)
+	if err != nil {
+		s.log.Error("Failed to revert song",
+			slog.Int("song_id", int(songID)),
+			slog.Int("revision", int(revision)),
+			logger.Err(err),
+		)
+		return nil, err
+	}
+
+	revert := dto.RefreshToDTO(*diff)
+	return &revert, nil
+}
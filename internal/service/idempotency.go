@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"songs_lib/config"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"songs_lib/pkg/logger"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+type IIdempotency interface {
+	Begin(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, key string, statusCode int, location string, body []byte) error
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyService remembers the responses to requests made with an
+// Idempotency-Key, so a retried request gets the original response.
+type IdempotencyService struct {
+	s     storage.Storage
+	log   *slog.Logger
+	ttl   time.Duration
+	lease time.Duration
+}
+
+func NewIdempotencyService(log *slog.Logger, s storage.Storage, cfg config.Idempotency) *IdempotencyService {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+
+	return &IdempotencyService{
+		s:     s,
+		log:   log,
+		ttl:   cfg.TTL,
+		lease: cfg.Lease,
+	}
+}
+
+// Begin reserves the key for a request. It returns nil when the request
+// should be processed and the stored record when its response should be
+// replayed. Reusing a key for a different request, or while the first one
+// is still running, is an error; once the lease of the first one has ended
+// the key is taken over.
+func (i *IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, error) {
+	now := time.Now()
+	record, err := i.s.ReserveIdempotencyKey(ctx, key, fingerprint, now, now.Add(-i.ttl), now.Add(-i.lease))
+	if err != nil {
+		i.log.Error("Failed to reserve idempotency key", logger.Err(err))
+		return nil, err
+	}
+
+	switch {
+	case record == nil:
+		return nil, nil
+	case record.Fingerprint != fingerprint:
+		return nil, ErrIdempotencyKeyReused
+	case record.StatusCode == 0:
+		return nil, ErrIdempotencyKeyInProgress
+	default:
+		return record, nil
+	}
+}
+
+func (i *IdempotencyService) Complete(ctx context.Context, key string, statusCode int, location string, body []byte) error {
+	if err := i.s.CompleteIdempotencyKey(ctx, key, statusCode, location, body); err != nil {
+		i.log.Error("Failed to store idempotent response", logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (i *IdempotencyService) Release(ctx context.Context, key string) error {
+	if err := i.s.ReleaseIdempotencyKey(ctx, key); err != nil && !errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+		i.log.Error("Failed to release idempotency key", logger.Err(err))
+		return err
+	}
+	return nil
+}
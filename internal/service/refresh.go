@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"songs_lib/config"
+	"songs_lib/internal/dto"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	external "songs_lib/internal/web/external"
+	"songs_lib/pkg/logger"
+	"time"
+)
+
+// ErrExternalAPI is returned when the external API failed or returned
+// details that can't be used.
+var ErrExternalAPI = errors.New("external API request failed")
+
+// refreshSorting pages through the library in insertion order, which a
+// refresh can't change.
+var refreshSorting = []model.SortKey{{Field: model.SortByInsertedAt}}
+
+// RefreshSong fetches the details of a song again and applies whatever
+// changed since it was stored. A song pending enrichment is refused with
+// storage.ErrSongPendingEnrichment.
+func (s *SongService) RefreshSong(ctx context.Context, songID uint) (*dto.RefreshDTO, error) {
+	song, err := s.s.GetSong(ctx, songID)
+	if err != nil {
+		s.log.Error("Failed to get song", logger.Err(err))
+		return nil, err
+	}
+	// its enrichment job fetches the details anyway
+	if song.Status == model.SongStatusPendingEnrichment {
+		return nil, storage.ErrSongPendingEnrichment
+	}
+
+	diff, err := refreshSong(ctx, s.s, s.fetcher, *song)
+	if err != nil {
+		s.log.Error("Failed to refresh song", slog.Int("song_id", int(songID)), logger.Err(err))
+		return nil, err
+	}
+
+	refresh := dto.RefreshToDTO(*diff)
+	return &refresh, nil
+}
+
+func refreshSong(ctx context.Context, s storage.Storage, fetcher SongFetcher, song model.Song) (*model.SongDiff, error) {
+	// details returned with ErrPartialData are not used either: they could
+	// replace what the failed provider returned last time
+	data, err := fetcher.FetchSong(ctx, song.Group, song.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExternalAPI, err)
+	}
+
+	details, err := parseFetchData(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExternalAPI, err)
+	}
+
+	return s.RefreshSong(ctx, song.ID, *details)
+}
+
+// Refresher periodically re-syncs every stored song with the external API.
+type Refresher struct {
+	log       *slog.Logger
+	s         storage.Storage
+	fetcher   SongFetcher
+	interval  time.Duration
+	batchSize int
+}
+
+func NewRefresher(log *slog.Logger, s storage.Storage, fetcher SongFetcher, cfg config.Refresh) *Refresher {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Refresher{
+		log:       log,
+		s:         s,
+		fetcher:   fetcher,
+		interval:  cfg.Interval,
+		batchSize: cfg.BatchSize,
+	}
+}
+
+// Run refreshes the library every interval until ctx is canceled. It
+// returns right away when the interval is zero.
+func (r *Refresher) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.RefreshAll(ctx); err != nil && ctx.Err() == nil {
+			r.log.Error("Failed to refresh songs", logger.Err(err))
+		}
+	}
+}
+
+// RefreshAll refreshes every song that is not waiting for enrichment and has
+// details the client didn't set. A song that fails is logged and skipped; the
+// run stops early while the external API circuit is open.
+func (r *Refresher) RefreshAll(ctx context.Context) error {
+	var after *model.SongCursor
+	var refreshed, changed, failed int
+
+	for {
+		songs, err := r.s.GetAllSongs(ctx, model.SongFilter{}, refreshSorting, after, r.batchSize, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, song := range songs {
+			// the external API has nothing to add to a song the client set
+			// every detail of
+			if song.Status == model.SongStatusPendingEnrichment || song.ClientFields.Has(model.AllSongFields) {
+				continue
+			}
+
+			diff, err := refreshSong(ctx, r.s, r.fetcher, song)
+			if errors.Is(err, external.ErrCircuitOpen) {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				r.log.Warn("Failed to refresh song", slog.Int("song_id", int(song.ID)), logger.Err(err))
+				failed++
+				continue
+			}
+
+			refreshed++
+			if !diff.Empty() {
+				changed++
+			}
+		}
+
+		if len(songs) < r.batchSize {
+			break
+		}
+		cursor := model.CursorFromSong(songs[len(songs)-1])
+		after = &cursor
+	}
+
+	r.log.Info("Songs refreshed",
+		slog.Int("refreshed", refreshed),
+		slog.Int("changed", changed),
+		slog.Int("failed", failed),
+	)
+	return nil
+}
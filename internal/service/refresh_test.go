@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"songs_lib/config"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"songs_lib/internal/storage/memory"
+	external "songs_lib/internal/web/external"
+	"testing"
+	"time"
+)
+
+// addFetchedSong stores a song as if its details came from the external API.
+func addFetchedSong(t *testing.T, s storage.Storage, name, link string, releaseDate time.Time, verses ...string) uint {
+	t.Helper()
+	id, err := s.AddSong(context.Background(), model.Song{
+		Group:       "Muse",
+		Name:        name,
+		Link:        link,
+		ReleaseDate: releaseDate,
+	}, verses)
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	return id
+}
+
+func TestRefreshSong(t *testing.T) {
+	fetcher := &fakeFetcher{data: &external.FetchData{
+		ReleaseDate: "07.09.2009",
+		Link:        "https://example.com/new",
+		Text:        "one\n\ntwo fixed",
+	}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := memory.NewMemoryStorage(log)
+	songs := NewSongService(log, s, fetcher)
+
+	releaseDate := time.Date(2009, 9, 7, 0, 0, 0, 0, time.UTC)
+	id := addFetchedSong(t, s, "Uprising", "https://example.com", releaseDate, "one", "two")
+
+	refresh, err := songs.RefreshSong(context.Background(), id)
+	if err != nil {
+		t.Fatalf("RefreshSong: %v", err)
+	}
+	if !refresh.Changed || len(refresh.Fields) != 1 || refresh.Fields[0].Field != "link" {
+		t.Errorf("fields = %+v, want only link changed", refresh.Fields)
+	}
+	if len(refresh.Verses) != 1 || refresh.Verses[0].VerseNumber != 2 || refresh.Verses[0].New != "two fixed" {
+		t.Errorf("verses = %+v, want verse 2 changed", refresh.Verses)
+	}
+
+	refresh, err = songs.RefreshSong(context.Background(), id)
+	if err != nil {
+		t.Fatalf("second RefreshSong: %v", err)
+	}
+	if refresh.Changed {
+		t.Errorf("second refresh = %+v, want no changes", refresh)
+	}
+
+	fetcher.err = &external.StatusError{StatusCode: http.StatusNotFound}
+	if _, err := songs.RefreshSong(context.Background(), id); !errors.Is(err, ErrExternalAPI) {
+		t.Errorf("RefreshSong error = %v, want ErrExternalAPI", err)
+	}
+
+	// details from the fallback alone don't replace the primary's
+	fetcher.data = &external.FetchData{Link: "https://fallback"}
+	fetcher.err = fmt.Errorf("%w: primary: %w", external.ErrPartialData, external.ErrCircuitOpen)
+	if _, err := songs.RefreshSong(context.Background(), id); !errors.Is(err, ErrExternalAPI) {
+		t.Errorf("RefreshSong error = %v, want ErrExternalAPI", err)
+	}
+	song, err := s.GetSong(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Link != "https://example.com/new" {
+		t.Errorf("link = %q, want it kept after a partial fetch", song.Link)
+	}
+}
+
+func TestRefresherRefreshAll(t *testing.T) {
+	fetcher := &fakeFetcher{data: &external.FetchData{Link: "https://example.com/new"}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := memory.NewMemoryStorage(log)
+	songs := NewSongService(log, s, fetcher)
+	refresher := NewRefresher(log, s, fetcher, config.Refresh{BatchSize: 2})
+
+	releaseDate := time.Date(2009, 9, 7, 0, 0, 0, 0, time.UTC)
+	var ids []uint
+	for _, name := range []string{"Uprising", "Resistance", "Undisclosed Desires"} {
+		ids = append(ids, addFetchedSong(t, s, name, "https://example.com", releaseDate))
+	}
+	pending, _, err := songs.AddSongForEnrichment(context.Background(), "Muse", "Exogenesis", "", time.Time{}, "")
+	if err != nil {
+		t.Fatalf("AddSongForEnrichment: %v", err)
+	}
+
+	if err := refresher.RefreshAll(context.Background()); err != nil {
+		t.Fatalf("RefreshAll: %v", err)
+	}
+
+	for _, id := range ids {
+		song, err := s.GetSong(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetSong: %v", err)
+		}
+		if song.Link != "https://example.com/new" {
+			t.Errorf("song %d link = %q, want refreshed", id, song.Link)
+		}
+	}
+
+	song, err := s.GetSong(context.Background(), pending)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != model.SongStatusPendingEnrichment || song.Link != "" {
+		t.Errorf("pending song = %+v, want it left to the enricher", song)
+	}
+
+	fetcher.err = external.ErrCircuitOpen
+	if err := refresher.RefreshAll(context.Background()); !errors.Is(err, external.ErrCircuitOpen) {
+		t.Errorf("RefreshAll error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestRefreshKeepsClientDetails(t *testing.T) {
+	fetcher := &fakeFetcher{data: &external.FetchData{
+		ReleaseDate: "07.09.2009",
+		Link:        "https://example.com/upstream",
+		Text:        "upstream one\n\nupstream two",
+	}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := memory.NewMemoryStorage(log)
+	songs := NewSongService(log, s, fetcher)
+	refresher := NewRefresher(log, s, fetcher, config.Refresh{})
+
+	manual, err := songs.AddSong(context.Background(), "Local Band", "Demo", "https://example.com/demo", time.Time{}, "mine")
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	partial, _, err := songs.AddSongForEnrichment(context.Background(), "Muse", "Uprising", "https://example.com/mine", time.Time{}, "")
+	if err != nil {
+		t.Fatalf("AddSongForEnrichment: %v", err)
+	}
+	enricher := NewEnricher(log, s, fetcher, config.Enrichment{})
+	if found, err := enricher.ProcessNext(context.Background()); !found || err != nil {
+		t.Fatalf("ProcessNext = %v, %v", found, err)
+	}
+
+	fetcher.data = &external.FetchData{
+		ReleaseDate: "08.09.2009",
+		Link:        "https://example.com/moved",
+		Text:        "fixed one\n\nfixed two",
+	}
+	calls := fetcher.calls
+	if err := refresher.RefreshAll(context.Background()); err != nil {
+		t.Fatalf("RefreshAll: %v", err)
+	}
+	if got := fetcher.calls - calls; got != 1 {
+		t.Errorf("RefreshAll fetched %d songs, want only the one with fetched details", got)
+	}
+
+	song, err := s.GetSong(context.Background(), manual)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Link != "https://example.com/demo" || !song.ReleaseDate.IsZero() {
+		t.Errorf("manual song = %+v, want it untouched", song)
+	}
+
+	song, err = s.GetSong(context.Background(), partial)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Link != "https://example.com/mine" || song.ReleaseDate.Format("2006-01-02") != "2009-09-08" {
+		t.Errorf("song = %+v, want the client link and the refreshed release date", song)
+	}
+	lyrics, err := s.GetAllSongLyrics(context.Background(), partial)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if len(lyrics) != 2 || lyrics[0].Text != "fixed one" {
+		t.Errorf("verses = %+v, want the refreshed ones", lyrics)
+	}
+
+	// a manual refresh keeps them as well
+	refresh, err := songs.RefreshSong(context.Background(), manual)
+	if err != nil {
+		t.Fatalf("RefreshSong: %v", err)
+	}
+	if refresh.Changed {
+		t.Errorf("refresh of the manual song = %+v, want no changes", refresh)
+	}
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"songs_lib/internal/dto"
 	"songs_lib/internal/model"
@@ -12,26 +14,42 @@ import (
 )
 
 type ISong interface {
-	AddSong(group, name, link string, releaseDate time.Time, text string) (uint, error)
-	DeleteSong(songID uint) error
-	GetLyrics(songID uint, limit, offset string) (*dto.SongDTO, error)
-	GetLibrary(filters map[string]string, limit, offset string) (*dto.LibraryDTO, error)
-	UpdateSong(songID uint, updates model.SongUpdate) (*dto.SongDTO, error)
+	AddSong(ctx context.Context, group, name, link string, releaseDate time.Time, text string) (uint, error)
+	AddSongForEnrichment(ctx context.Context, group, name, link string, releaseDate time.Time, text string) (uint, model.SongStatus, error)
+	UpsertSong(ctx context.Context, group, name, link string, releaseDate time.Time, text string, enrich bool) (*dto.UpsertSongDTO, error)
+	GetEnrichment(ctx context.Context, songID uint) (*dto.EnrichmentDTO, error)
+	GetSong(ctx context.Context, songID uint) (*dto.SongDTO, error)
+	DeleteSong(ctx context.Context, songID uint) error
+	GetLyrics(ctx context.Context, songID uint, limit, offset string) (*dto.LyricsPageDTO, error)
+	GetLibrary(ctx context.Context, filter model.SongFilter, sort, cursor, limit, offset string, includeVerses bool) (*dto.LibraryDTO, error)
+	UpdateSong(ctx context.Context, songID uint, updates model.SongUpdate) (*dto.SongDTO, error)
+	AddVerse(ctx context.Context, songID uint, position uint, text string) (*dto.LyricsDTO, error)
+	DeleteVerse(ctx context.Context, songID uint, verseNumber uint) error
+	ReorderVerses(ctx context.Context, songID uint, order []uint) error
+	Search(ctx context.Context, query, limit, offset string) (*dto.SearchDTO, error)
+	RefreshSong(ctx context.Context, songID uint) (*dto.RefreshDTO, error)
+	GetHistory(ctx context.Context, songID uint, limit, offset string) (*dto.HistoryDTO, error)
+	RevertSong(ctx context.Context, songID uint, revision uint) (*dto.RefreshDTO, error)
+	GetTrash(ctx context.Context, limit, offset string) (*dto.TrashDTO, error)
+	RestoreSong(ctx context.Context, songID uint) (*dto.SongDTO, error)
 }
 
 type SongService struct {
-	s   storage.Storage
-	log *slog.Logger
+	s       storage.Storage
+	fetcher SongFetcher
+	log     *slog.Logger
 }
 
-func NewSongService(log *slog.Logger, s storage.Storage) *SongService {
+func NewSongService(log *slog.Logger, s storage.Storage, fetcher SongFetcher) *SongService {
 	return &SongService{
-		log: log,
-		s:   s,
+		log:     log,
+		s:       s,
+		fetcher: fetcher,
 	}
 }
 
 func (s *SongService) AddSong(
+	ctx context.Context,
 	group,
 	name,
 	link string,
@@ -43,46 +61,186 @@ func (s *SongService) AddSong(
 		verses = splitTextIntoVerses(text)
 	}
 
+	// the external API is not asked, so refreshes must not fill in the
+	// details left empty either
 	song := model.Song{
-		Group:       group,
-		Name:        name,
-		ReleaseDate: releaseDate,
-		Link:        link,
+		Group:        group,
+		Name:         name,
+		ReleaseDate:  releaseDate,
+		Link:         link,
+		ClientFields: model.AllSongFields,
 	}
 
-	songID, err := s.s.AddSong(song, verses)
+	songID, err := s.s.AddSong(ctx, song, verses)
 	if err != nil {
-		s.log.Error("Failed to add song", logger.Err(err))
+		var exists *storage.ErrSongExists
+		if !errors.As(err, &exists) {
+			s.log.Error("Failed to add song", logger.Err(err))
+		}
 		return 0, err
 	}
 	return songID, nil
 }
 
-func (s *SongService) DeleteSong(songID uint) error {
-	if err := s.s.DeleteSong(songID); err != nil {
+// AddSongForEnrichment stores a song right away with the details the client
+// sent and queues a job that fetches the missing ones from the external API.
+// The job never overwrites what was sent, and a song sent with every detail
+// is stored ready without a job.
+func (s *SongService) AddSongForEnrichment(
+	ctx context.Context,
+	group,
+	name,
+	link string,
+	releaseDate time.Time,
+	text string,
+) (uint, model.SongStatus, error) {
+	var verses []string
+	if len(text) != 0 {
+		verses = splitTextIntoVerses(text)
+	}
+
+	status := model.SongStatusPendingEnrichment
+	if link != "" && !releaseDate.IsZero() && len(verses) > 0 {
+		status = model.SongStatusReady
+	}
+
+	songID, err := s.s.AddSong(ctx, model.Song{
+		Group:        group,
+		Name:         name,
+		Link:         link,
+		ReleaseDate:  releaseDate,
+		Status:       status,
+		ClientFields: model.SongEnrichment{Link: link, ReleaseDate: releaseDate, Verses: verses}.Fields(),
+	}, verses)
+	if err != nil {
+		var exists *storage.ErrSongExists
+		if !errors.As(err, &exists) {
+			s.log.Error("Failed to add song", logger.Err(err))
+		}
+		return 0, "", err
+	}
+	return songID, status, nil
+}
+
+// UpsertSong adds a song like AddSongForEnrichment, or AddSong when enrich is
+// false. If a song with the same group and name exists, the details sent
+// replace its stored ones instead; empty details are kept. A song in the trash
+// is left alone and reported as ErrSongExists.
+func (s *SongService) UpsertSong(
+	ctx context.Context,
+	group,
+	name,
+	link string,
+	releaseDate time.Time,
+	text string,
+	enrich bool,
+) (*dto.UpsertSongDTO, error) {
+	status := model.SongStatusReady
+	var songID uint
+	var err error
+	if enrich {
+		songID, status, err = s.AddSongForEnrichment(ctx, group, name, link, releaseDate, text)
+	} else {
+		songID, err = s.AddSong(ctx, group, name, link, releaseDate, text)
+	}
+	if err == nil {
+		return &dto.UpsertSongDTO{ID: songID, Created: true, Status: string(status)}, nil
+	}
+
+	// a song in the trash has to be restored before it can be updated
+	var exists *storage.ErrSongExists
+	if !errors.As(err, &exists) || exists.Deleted {
+		return nil, err
+	}
+
+	details := model.SongEnrichment{Link: link, ReleaseDate: releaseDate}
+	if len(text) != 0 {
+		details.Verses = splitTextIntoVerses(text)
+	}
+	diff, err := s.s.SetSongDetails(ctx, exists.ID, details)
+	if err != nil {
+		s.log.Error("Failed to update song", slog.Int("song_id", int(exists.ID)), logger.Err(err))
+		return nil, err
+	}
+
+	changes := dto.RefreshToDTO(*diff)
+	return &dto.UpsertSongDTO{ID: exists.ID, Status: string(diff.Song.Status), Changes: &changes}, nil
+}
+
+func (s *SongService) GetEnrichment(ctx context.Context, songID uint) (*dto.EnrichmentDTO, error) {
+	song, err := s.s.GetSong(ctx, songID)
+	if err != nil {
+		s.log.Error("Failed to get song", logger.Err(err))
+		return nil, err
+	}
+
+	// songs added with their details never had a job
+	job, err := s.s.GetEnrichmentJob(ctx, songID)
+	if err != nil && !errors.Is(err, storage.ErrEnrichmentJobNotFound) {
+		s.log.Error("Failed to get enrichment job", logger.Err(err))
+		return nil, err
+	}
+
+	enrichment := dto.EnrichmentToDTO(*song, job)
+	return &enrichment, nil
+}
+
+func (s *SongService) GetSong(ctx context.Context, songID uint) (*dto.SongDTO, error) {
+	song, err := s.s.GetSong(ctx, songID)
+	if err != nil {
+		s.log.Error("Failed to get song", logger.Err(err))
+		return nil, err
+	}
+
+	lyrics, err := s.s.GetAllSongLyrics(ctx, songID)
+	if err != nil {
+		s.log.Error("Failed to get song lyrics", logger.Err(err))
+		return nil, err
+	}
+
+	songDTO := dto.SongToDTO(*song, lyrics)
+	return &songDTO, nil
+}
+
+func (s *SongService) DeleteSong(ctx context.Context, songID uint) error {
+	if err := s.s.DeleteSong(ctx, songID); err != nil {
 		s.log.Error("Failed to delete song", slog.Int("song_id", int(songID)), logger.Err(err))
 		return err
 	}
 	return nil
 }
 
-func (s *SongService) GetLyrics(songID uint, limit, offset string) (*dto.SongDTO, error) {
+func (s *SongService) GetLyrics(ctx context.Context, songID uint, limit, offset string) (*dto.LyricsPageDTO, error) {
 	limitInt, offsetInt := getLimitAndOffset(limit, offset)
-	song, err := s.s.GetSong(songID)
+	song, err := s.s.GetSong(ctx, songID)
 	if err != nil {
 		s.log.Error("Failed to get song", logger.Err(err))
 		return nil, err
 	}
 
-	lyrics, err := s.s.GetLyrics(songID, limitInt, offsetInt)
+	lyrics, err := s.s.GetLyrics(ctx, songID, limitInt, offsetInt)
 	if err != nil {
 		s.log.Error("Failed to get lyrics", logger.Err(err))
 		return nil, err
 	}
 
-	songDTO := &dto.SongDTO{
-		Group: song.Group,
-		Name:  song.Name,
+	total, err := s.s.CountLyrics(ctx, songID)
+	if err != nil {
+		s.log.Error("Failed to count lyrics", logger.Err(err))
+		return nil, err
+	}
+
+	songDTO := &dto.LyricsPageDTO{
+		SongDTO: dto.SongDTO{
+			Group: song.Group,
+			Name:  song.Name,
+		},
+		PaginationDTO: dto.PaginationDTO{
+			Total:   total,
+			Limit:   limitInt,
+			Offset:  offsetInt,
+			HasMore: limitInt > 0 && offsetInt+len(lyrics) < total,
+		},
 	}
 
 	for _, lyric := range lyrics {
@@ -96,46 +254,97 @@ func (s *SongService) GetLyrics(songID uint, limit, offset string) (*dto.SongDTO
 }
 
 func (s *SongService) GetLibrary(
-	filters map[string]string,
+	ctx context.Context,
+	filter model.SongFilter,
+	sort,
+	cursor,
 	limit,
 	offset string,
+	includeVerses bool,
 ) (*dto.LibraryDTO, error) {
 	limitInt, offsetInt := getLimitAndOffset(limit, offset)
 	songsDTO := make([]dto.SongDTO, 0)
 
-	songs, err := s.s.GetAllSongs(filters, limitInt, offsetInt)
+	sortKeys, err := parseSort(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var after *model.SongCursor
+	if cursor != "" {
+		if after, err = decodeCursor(cursor, sortKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	// one extra song tells whether there is a next page
+	fetchLimit := limitInt
+	if limitInt > 0 {
+		fetchLimit++
+	}
+
+	songs, err := s.s.GetAllSongs(ctx, filter, sortKeys, after, fetchLimit, offsetInt)
 	if err != nil {
 		s.log.Error("Failed to get all songs", logger.Err(err))
 		return nil, err
 	}
 
-	for _, song := range songs {
-		lyrics, err := s.s.GetAllSongLyrics(song.ID)
+	var nextCursor string
+	if limitInt > 0 && len(songs) > limitInt {
+		songs = songs[:limitInt]
+		nextCursor = encodeCursor(songs[len(songs)-1], sortKeys)
+	}
+
+	total, err := s.s.CountSongs(ctx, filter)
+	if err != nil {
+		s.log.Error("Failed to count songs", logger.Err(err))
+		return nil, err
+	}
+
+	var lyrics map[uint][]model.Lyrics
+	if includeVerses && len(songs) > 0 {
+		songIDs := make([]uint, 0, len(songs))
+		for _, song := range songs {
+			songIDs = append(songIDs, song.ID)
+		}
+
+		lyrics, err = s.s.GetLyricsForSongs(ctx, songIDs)
 		if err != nil {
-			s.log.Error("Failed to get all song lyrics", logger.Err(err))
+			s.log.Error("Failed to get song lyrics", logger.Err(err))
 			return nil, err
 		}
+	}
 
-		songsDTO = append(songsDTO, dto.SongToDTO(song, lyrics))
+	for _, song := range songs {
+		songsDTO = append(songsDTO, dto.SongToDTO(song, lyrics[song.ID]))
 	}
 
-	return &dto.LibraryDTO{Songs: songsDTO}, nil
+	return &dto.LibraryDTO{
+		Songs:      songsDTO,
+		NextCursor: nextCursor,
+		PaginationDTO: dto.PaginationDTO{
+			Total:   total,
+			Limit:   limitInt,
+			Offset:  offsetInt,
+			HasMore: nextCursor != "",
+		},
+	}, nil
 }
 
-func (s *SongService) UpdateSong(songID uint, updates model.SongUpdate) (*dto.SongDTO, error) {
-	err := s.s.UpdateSong(songID, updates)
+func (s *SongService) UpdateSong(ctx context.Context, songID uint, updates model.SongUpdate) (*dto.SongDTO, error) {
+	err := s.s.UpdateSong(ctx, songID, updates)
 	if err != nil {
 		s.log.Error("Failed to update song", logger.Err(err))
 		return nil, err
 	}
 
-	song, err := s.s.GetSong(songID)
+	song, err := s.s.GetSong(ctx, songID)
 	if err != nil {
 		s.log.Error("Failed to get song", logger.Err(err))
 		return nil, err
 	}
 
-	lyrics, err := s.s.GetAllSongLyrics(songID)
+	lyrics, err := s.s.GetAllSongLyrics(ctx, songID)
 	if err != nil {
 		s.log.Error("Failed to get song lyrics", logger.Err(err))
 		return nil, err
@@ -146,6 +355,53 @@ func (s *SongService) UpdateSong(songID uint, updates model.SongUpdate) (*dto.So
 	return &updatedSong, nil
 }
 
+func (s *SongService) AddVerse(ctx context.Context, songID uint, position uint, text string) (*dto.LyricsDTO, error) {
+	text = strings.TrimSpace(text)
+	verseNumber, err := s.s.AddVerse(ctx, songID, position, text)
+	if err != nil {
+		s.log.Error("Failed to add verse", slog.Int("song_id", int(songID)), logger.Err(err))
+		return nil, err
+	}
+
+	return &dto.LyricsDTO{
+		VerseNumber: verseNumber,
+		Text:        text,
+	}, nil
+}
+
+func (s *SongService) DeleteVerse(ctx context.Context, songID uint, verseNumber uint) error {
+	if err := s.s.DeleteVerse(ctx, songID, verseNumber); err != nil {
+		s.log.Error("Failed to delete verse", slog.Int("song_id", int(songID)), logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (s *SongService) ReorderVerses(ctx context.Context, songID uint, order []uint) error {
+	if err := s.s.ReorderVerses(ctx, songID, order); err != nil {
+		s.log.Error("Failed to reorder verses", slog.Int("song_id", int(songID)), logger.Err(err))
+		return err
+	}
+	return nil
+}
+
+func (s *SongService) Search(ctx context.Context, query, limit, offset string) (*dto.SearchDTO, error) {
+	limitInt, offsetInt := getLimitAndOffset(limit, offset)
+
+	results, err := s.s.SearchLyrics(ctx, query, limitInt, offsetInt)
+	if err != nil {
+		s.log.Error("Failed to search lyrics", slog.String("query", query), logger.Err(err))
+		return nil, err
+	}
+
+	resultsDTO := make([]dto.SearchResultDTO, 0, len(results))
+	for _, result := range results {
+		resultsDTO = append(resultsDTO, dto.SearchResultToDTO(result))
+	}
+
+	return &dto.SearchDTO{Results: resultsDTO}, nil
+}
+
 func splitTextIntoVerses(text string) []string {
 	verses := strings.Split(text, "\n\n")
 
@@ -175,4 +431,3 @@ func getLimitAndOffset(limit, offset string) (int, int) {
 
 	return limitInt, offsetInt
 }
-
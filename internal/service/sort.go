@@ -0,0 +1,49 @@
+package service
+
+import (
+	"fmt"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"strings"
+)
+
+// parseSort parses a comma separated list of sort fields, each optionally
+// prefixed with "-" for descending order, e.g. "release_date,-inserted_at".
+func parseSort(sort string) ([]model.SortKey, error) {
+	sort = strings.TrimSpace(sort)
+	if sort == "" {
+		return nil, nil
+	}
+
+	var keys []model.SortKey
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		key := model.SortKey{}
+		if strings.HasPrefix(field, "-") {
+			key.Desc = true
+			field = field[1:]
+		}
+		if field == "" {
+			return nil, fmt.Errorf("%w: empty field", storage.ErrInvalidSort)
+		}
+		key.Field = model.SortField(field)
+		keys = append(keys, key)
+	}
+
+	if err := storage.ValidateSort(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func formatSort(sort []model.SortKey) string {
+	fields := make([]string, 0, len(sort))
+	for _, key := range sort {
+		if key.Desc {
+			fields = append(fields, "-"+string(key.Field))
+		} else {
+			fields = append(fields, string(key.Field))
+		}
+	}
+	return strings.Join(fields, ",")
+}
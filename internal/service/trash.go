@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"songs_lib/config"
+	"songs_lib/internal/dto"
+	"songs_lib/internal/storage"
+	"songs_lib/pkg/logger"
+	"time"
+)
+
+// GetTrash returns a page of the deleted songs, most recently deleted first.
+func (s *SongService) GetTrash(ctx context.Context, limit, offset string) (*dto.TrashDTO, error) {
+	limitInt, offsetInt := getLimitAndOffset(limit, offset)
+
+	songs, err := s.s.GetDeletedSongs(ctx, limitInt, offsetInt)
+	if err != nil {
+		s.log.Error("Failed to get deleted songs", logger.Err(err))
+		return nil, err
+	}
+
+	total, err := s.s.CountDeletedSongs(ctx)
+	if err != nil {
+		s.log.Error("Failed to count deleted songs", logger.Err(err))
+		return nil, err
+	}
+
+	trash := &dto.TrashDTO{
+		Songs: make([]dto.SongDTO, 0, len(songs)),
+		PaginationDTO: dto.PaginationDTO{
+			Total:   total,
+			Limit:   limitInt,
+			Offset:  offsetInt,
+			HasMore: limitInt > 0 && offsetInt+len(songs) < total,
+		},
+	}
+	for _, song := range songs {
+		trash.Songs = append(trash.Songs, dto.SongToDTO(song, nil))
+	}
+	return trash, nil
+}
+
+// RestoreSong moves a song out of the trash and returns it with its verses.
+func (s *SongService) RestoreSong(ctx context.Context, songID uint) (*dto.SongDTO, error) {
+	if err := s.s.RestoreSong(ctx, songID); err != nil {
+		s.log.Error("Failed to restore song", slog.Int("song_id", int(songID)), logger.Err(err))
+		return nil, err
+	}
+	return s.GetSong(ctx, songID)
+}
+
+// Purger periodically removes the songs that have been in the trash longer
+// than the retention period.
+type Purger struct {
+	log       *slog.Logger
+	s         storage.Storage
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(log *slog.Logger, s storage.Storage, cfg config.Trash) *Purger {
+	return &Purger{
+		log:       log,
+		s:         s,
+		retention: cfg.Retention,
+		interval:  cfg.PurgeInterval,
+	}
+}
+
+// Run purges the trash every interval until ctx is canceled. It returns right
+// away when the retention or the interval is zero.
+func (p *Purger) Run(ctx context.Context) {
+	if p.retention <= 0 || p.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := p.Purge(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			p.log.Error("Failed to purge deleted songs", logger.Err(err))
+		}
+	}
+}
+
+// Purge removes the songs deleted more than the retention period before now.
+func (p *Purger) Purge(ctx context.Context, now time.Time) error {
+	purged, err := p.s.PurgeDeletedSongs(ctx, now.Add(-p.retention))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		p.log.Info("Deleted songs purged", slog.Int("purged", purged))
+	}
+	return nil
+}
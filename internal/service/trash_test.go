@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"songs_lib/config"
+	"songs_lib/internal/storage"
+	"songs_lib/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+func TestPurgerPurge(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := memory.NewMemoryStorage(log)
+	songs := NewSongService(log, s, &fakeFetcher{})
+	purger := NewPurger(log, s, config.Trash{Retention: time.Hour})
+
+	id, err := songs.AddSong(context.Background(), "Muse", "Uprising", "https://example.com", time.Time{}, "one")
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	if err := songs.DeleteSong(context.Background(), id); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	// a song in the trash is not brought back by an upsert
+	_, err = songs.UpsertSong(context.Background(), "Muse", "Uprising", "", time.Time{}, "two", false)
+	var exists *storage.ErrSongExists
+	if !errors.As(err, &exists) || !exists.Deleted {
+		t.Errorf("UpsertSong error = %v, want ErrSongExists for a deleted song", err)
+	}
+
+	if err := purger.Purge(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	trash, err := songs.GetTrash(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("GetTrash: %v", err)
+	}
+	if trash.Total != 1 || len(trash.Songs) != 1 || trash.Songs[0].DeletedAt == nil {
+		t.Fatalf("trash = %+v, want the song kept within the retention period", trash)
+	}
+
+	if err := purger.Purge(context.Background(), time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if _, err := songs.RestoreSong(context.Background(), id); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("RestoreSong(purged) error = %v, want ErrSongNotFound", err)
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"songs_lib/internal/model"
+	"time"
+)
+
+// StatusOf returns the status a song is stored with, ready unless set.
+func StatusOf(song model.Song) model.SongStatus {
+	if song.Status == "" {
+		return model.SongStatusReady
+	}
+	return song.Status
+}
+
+// DiffSong compares a stored song and its verses, ordered by number, with
+// freshly fetched details. Empty fetched values keep the stored ones, so an
+// incomplete response never erases data, and the fields the client set are
+// kept as well. Verses are compared by number.
+func DiffSong(song model.Song, lyrics []model.Lyrics, details model.SongEnrichment) model.SongDiff {
+	diff := model.SongDiff{Song: song}
+
+	if song.ClientFields.Has(model.FieldLink) {
+		details.Link = ""
+	}
+	if song.ClientFields.Has(model.FieldReleaseDate) {
+		details.ReleaseDate = time.Time{}
+	}
+	if song.ClientFields.Has(model.FieldVerses) {
+		details.Verses = nil
+	}
+
+	if details.Link != "" && details.Link != song.Link {
+		diff.Fields = append(diff.Fields, model.FieldChange{Field: "link", Old: song.Link, New: details.Link})
+		diff.Song.Link = details.Link
+	}
+
+	if !details.ReleaseDate.IsZero() && !details.ReleaseDate.Equal(song.ReleaseDate) {
+		var old string
+		if !song.ReleaseDate.IsZero() {
+			old = song.ReleaseDate.Format("2006-01-02")
+		}
+		diff.Fields = append(diff.Fields, model.FieldChange{
+			Field: "release_date",
+			Old:   old,
+			New:   details.ReleaseDate.Format("2006-01-02"),
+		})
+		diff.Song.ReleaseDate = details.ReleaseDate
+	}
+
+	if len(details.Verses) == 0 {
+		return diff
+	}
+
+	for i := 0; i < max(len(lyrics), len(details.Verses)); i++ {
+		change := model.VerseChange{VerseNumber: uint(i + 1)}
+		switch {
+		case i >= len(lyrics):
+			change.Kind = model.ChangeAdded
+			change.New = details.Verses[i]
+		case i >= len(details.Verses):
+			change.Kind = model.ChangeRemoved
+			change.Old = lyrics[i].Text
+		case lyrics[i].Text != details.Verses[i]:
+			change.Kind = model.ChangeModified
+			change.Old = lyrics[i].Text
+			change.New = details.Verses[i]
+		default:
+			continue
+		}
+		diff.Verses = append(diff.Verses, change)
+	}
+
+	return diff
+}
+
+// DiffClientDetails is DiffSong for details the client sent: they replace the
+// stored ones even where the client set them before, and become client fields.
+func DiffClientDetails(song model.Song, lyrics []model.Lyrics, details model.SongEnrichment) model.SongDiff {
+	fields := song.ClientFields
+	song.ClientFields = 0
+
+	diff := DiffSong(song, lyrics, details)
+	diff.Song.ClientFields = fields | details.Fields()
+	return diff
+}
+
+// ChangedFields returns the details of the song that a diff changes. Edits
+// mark them as client fields, so a refresh doesn't undo them.
+func ChangedFields(diff model.SongDiff) model.SongFields {
+	var fields model.SongFields
+	for _, change := range diff.Fields {
+		switch change.Field {
+		case "link":
+			fields |= model.FieldLink
+		case "release_date":
+			fields |= model.FieldReleaseDate
+		}
+	}
+	if len(diff.Verses) > 0 {
+		fields |= model.FieldVerses
+	}
+	return fields
+}
@@ -0,0 +1,250 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+// addEnrichmentJob must be called with s.mu held.
+func (s *MemoryStorage) addEnrichmentJob(songID uint, now time.Time) {
+	job := model.EnrichmentJob{
+		ID:        s.nextJobID,
+		SongID:    songID,
+		Status:    model.EnrichmentJobPending,
+		RunAt:     now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.nextJobID++
+
+	s.jobs[job.ID] = job
+	s.songJobs[songID] = job.ID
+}
+
+func (s *MemoryStorage) ClaimEnrichmentJob(ctx context.Context, now time.Time, lease time.Duration) (*model.EnrichmentJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due *model.EnrichmentJob
+	for _, job := range s.jobs {
+		if job.Status != model.EnrichmentJobPending && job.Status != model.EnrichmentJobRunning {
+			continue
+		}
+		if job.RunAt.After(now) {
+			continue
+		}
+		if _, ok := s.songs[job.SongID]; !ok {
+			// the song is in the trash
+			continue
+		}
+		if due == nil || cmp.Or(job.RunAt.Compare(due.RunAt), cmp.Compare(job.ID, due.ID)) < 0 {
+			job := job
+			due = &job
+		}
+	}
+	if due == nil {
+		return nil, storage.ErrEnrichmentJobNotFound
+	}
+
+	due.Status = model.EnrichmentJobRunning
+	due.Attempts++
+	due.RunAt = now.Add(lease)
+	due.UpdatedAt = now
+	s.jobs[due.ID] = *due
+
+	return due, nil
+}
+
+func (s *MemoryStorage) CompleteEnrichmentJob(ctx context.Context, jobID uint, details model.SongEnrichment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return storage.ErrEnrichmentJobNotFound
+	}
+
+	before, ok := s.songs[job.SongID]
+	if !ok {
+		return storage.ErrSongNotFound
+	}
+
+	// keep whatever was set on the song in the meantime
+	song := before
+	if song.Link == "" {
+		song.Link = details.Link
+	}
+	if song.ReleaseDate.IsZero() {
+		song.ReleaseDate = truncateDate(details.ReleaseDate)
+	}
+	song.Status = model.SongStatusReady
+	s.songs[job.SongID] = song
+
+	beforeLyrics := s.lyrics[job.SongID]
+	if len(beforeLyrics) == 0 {
+		lyrics := make([]model.Lyrics, 0, len(details.Verses))
+		for i, verse := range details.Verses {
+			lyrics = append(lyrics, model.Lyrics{
+				SongID:      job.SongID,
+				VerseNumber: uint(i + 1),
+				Text:        verse,
+			})
+		}
+		s.lyrics[job.SongID] = lyrics
+	}
+	s.addRevision(ctx, job.SongID, storage.DiffRevision(before, beforeLyrics, song, s.lyrics[job.SongID]))
+
+	job.Status = model.EnrichmentJobDone
+	job.LastError = ""
+	job.UpdatedAt = time.Now().UTC()
+	s.jobs[jobID] = job
+
+	s.log.Info("Song enriched successfully", slog.Int("song_id", int(job.SongID)))
+	return nil
+}
+
+func (s *MemoryStorage) RetryEnrichmentJob(ctx context.Context, jobID uint, lastError string, runAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return storage.ErrEnrichmentJobNotFound
+	}
+
+	job.Status = model.EnrichmentJobPending
+	job.LastError = lastError
+	job.RunAt = runAt
+	job.UpdatedAt = time.Now().UTC()
+	s.jobs[jobID] = job
+	return nil
+}
+
+func (s *MemoryStorage) FailEnrichmentJob(ctx context.Context, jobID uint, lastError string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return storage.ErrEnrichmentJobNotFound
+	}
+
+	job.Status = model.EnrichmentJobFailed
+	job.LastError = lastError
+	job.UpdatedAt = time.Now().UTC()
+	s.jobs[jobID] = job
+
+	if song, ok := s.songs[job.SongID]; ok {
+		song.Status = model.SongStatusEnrichmentFailed
+		s.songs[job.SongID] = song
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetEnrichmentJob(ctx context.Context, songID uint) (*model.EnrichmentJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobID, ok := s.songJobs[songID]
+	if _, active := s.songs[songID]; !ok || !active {
+		return nil, storage.ErrEnrichmentJobNotFound
+	}
+	job := s.jobs[jobID]
+	return &job, nil
+}
+
+func (s *MemoryStorage) RefreshSong(ctx context.Context, songID uint, details model.SongEnrichment) (*model.SongDiff, error) {
+	diff, err := s.applySongDetails(ctx, songID, details, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !diff.Empty() {
+		s.log.Info("Song refreshed",
+			slog.Int("song_id", int(songID)),
+			slog.Int("fields", len(diff.Fields)),
+			slog.Int("verses", len(diff.Verses)),
+		)
+	}
+	return diff, nil
+}
+
+func (s *MemoryStorage) SetSongDetails(ctx context.Context, songID uint, details model.SongEnrichment) (*model.SongDiff, error) {
+	diff, err := s.applySongDetails(ctx, songID, details, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !diff.Empty() {
+		s.log.Info("Song details updated",
+			slog.Int("song_id", int(songID)),
+			slog.Int("fields", len(diff.Fields)),
+			slog.Int("verses", len(diff.Verses)),
+		)
+	}
+	return diff, nil
+}
+
+// applySongDetails applies what DiffSong finds changed. A refresh is refused
+// while the song is pending enrichment and marks it ready; otherwise the
+// details come from the client, as in DiffClientDetails, and the status is
+// kept.
+func (s *MemoryStorage) applySongDetails(ctx context.Context, songID uint, details model.SongEnrichment, refresh bool) (*model.SongDiff, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.songs[songID]
+	if !ok {
+		return nil, storage.ErrSongNotFound
+	}
+	if refresh && song.Status == model.SongStatusPendingEnrichment {
+		return nil, storage.ErrSongPendingEnrichment
+	}
+
+	var diff model.SongDiff
+	if refresh {
+		diff = storage.DiffSong(song, s.lyrics[songID], details)
+		diff.Song.Status = model.SongStatusReady
+	} else {
+		diff = storage.DiffClientDetails(song, s.lyrics[songID], details)
+	}
+	s.songs[songID] = diff.Song
+
+	if len(diff.Verses) > 0 {
+		lyrics := make([]model.Lyrics, 0, len(details.Verses))
+		for i, verse := range details.Verses {
+			lyrics = append(lyrics, model.Lyrics{SongID: songID, VerseNumber: uint(i + 1), Text: verse})
+		}
+		s.lyrics[songID] = lyrics
+	}
+	s.addRevision(ctx, songID, diff)
+	return &diff, nil
+}
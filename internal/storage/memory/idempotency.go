@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+func (s *MemoryStorage) ReserveIdempotencyKey(
+	ctx context.Context,
+	key,
+	fingerprint string,
+	now,
+	expiredBefore,
+	staleBefore time.Time,
+) (*model.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, record := range s.idempotencyKeys {
+		if record.CreatedAt.Before(expiredBefore) || (record.StatusCode == 0 && record.CreatedAt.Before(staleBefore)) {
+			delete(s.idempotencyKeys, k)
+		}
+	}
+
+	if record, ok := s.idempotencyKeys[key]; ok {
+		return &record, nil
+	}
+
+	s.idempotencyKeys[key] = model.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+	}
+	return nil, nil
+}
+
+func (s *MemoryStorage) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, location string, body []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.idempotencyKeys[key]
+	if !ok {
+		return storage.ErrIdempotencyKeyNotFound
+	}
+	record.StatusCode = statusCode
+	record.Location = location
+	record.Body = append([]byte(nil), body...)
+	s.idempotencyKeys[key] = record
+	return nil
+}
+
+func (s *MemoryStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.idempotencyKeys[key]; !ok {
+		return storage.ErrIdempotencyKeyNotFound
+	}
+	delete(s.idempotencyKeys, key)
+	return nil
+}
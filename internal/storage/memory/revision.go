@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+// addRevision records the changes of a diff as the next revision of the
+// song. The caller must hold the write lock.
+func (s *MemoryStorage) addRevision(ctx context.Context, songID uint, diff model.SongDiff) {
+	if diff.Empty() {
+		return
+	}
+
+	revisions := s.revisions[songID]
+	s.revisions[songID] = append(revisions, model.SongRevision{
+		SongID:    songID,
+		Number:    uint(len(revisions) + 1),
+		Actor:     storage.ActorFrom(ctx),
+		CreatedAt: time.Now().UTC(),
+		Fields:    diff.Fields,
+		Verses:    diff.Verses,
+	})
+}
+
+// addClientRevision records an edit of the client and marks the details it
+// changed as client fields.
+func (s *MemoryStorage) addClientRevision(ctx context.Context, songID uint, diff model.SongDiff) {
+	if song, ok := s.songs[songID]; ok {
+		song.ClientFields |= storage.ChangedFields(diff)
+		s.songs[songID] = song
+	}
+	s.addRevision(ctx, songID, diff)
+}
+
+func (s *MemoryStorage) GetSongRevisions(ctx context.Context, songID uint, limit, offset int) ([]model.SongRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 {
+		return nil, fmt.Errorf("LIMIT must not be negative")
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("OFFSET must not be negative")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.songs[songID]; !ok {
+		return nil, storage.ErrSongNotFound
+	}
+
+	revisions := slices.Clone(s.revisions[songID])
+	slices.Reverse(revisions)
+	return paginate(revisions, limit, offset), nil
+}
+
+func (s *MemoryStorage) CountSongRevisions(ctx context.Context, songID uint) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.revisions[songID]), nil
+}
+
+func (s *MemoryStorage) RevertSong(ctx context.Context, songID uint, revision uint) (*model.SongDiff, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.songs[songID]
+	if !ok {
+		return nil, storage.ErrSongNotFound
+	}
+
+	revisions := s.revisions[songID]
+	if revision == 0 || revision > uint(len(revisions)) {
+		return nil, fmt.Errorf("%w: %d", storage.ErrRevisionNotFound, revision)
+	}
+
+	undone := slices.Clone(revisions[revision-1:])
+	slices.Reverse(undone)
+	lyrics := s.lyrics[songID]
+	reverted, revertedLyrics, err := storage.RevertRevisions(song, lyrics, undone)
+	if err != nil {
+		return nil, err
+	}
+
+	oldKey := songKey{group: song.Group, name: song.Name}
+	newKey := songKey{group: reverted.Group, name: reverted.Name}
+	if newKey != oldKey {
+		if id, ok := s.keys[newKey]; ok {
+			_, deleted := s.trash[id]
+			return nil, &storage.ErrSongExists{ID: id, Deleted: deleted}
+		}
+		delete(s.keys, oldKey)
+		s.keys[newKey] = songID
+	}
+
+	diff := storage.DiffRevision(song, lyrics, reverted, revertedLyrics)
+	s.songs[songID] = reverted
+	s.lyrics[songID] = revertedLyrics
+	s.addClientRevision(ctx, songID, diff)
+
+	s.log.Info("Song reverted",
+		slog.Int("song_id", int(songID)),
+		slog.Int("revision", int(revision)),
+	)
+	return &diff, nil
+}
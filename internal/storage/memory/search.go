@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+)
+
+func (s *MemoryStorage) SearchLyrics(
+	ctx context.Context,
+	query string,
+	limit,
+	offset int,
+) ([]model.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("LIMIT and OFFSET must not be negative")
+	}
+
+	q := storage.ParseSearchQuery(query)
+	if q.Empty() {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	songs := make([]model.Song, 0, len(s.songs))
+	for _, song := range s.songs {
+		songs = append(songs, song)
+	}
+
+	return paginate(storage.SearchSongs(q, songs, s.lyrics), limit, offset), nil
+}
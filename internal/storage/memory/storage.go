@@ -0,0 +1,511 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type songKey struct {
+	group string
+	name  string
+}
+
+type MemoryStorage struct {
+	mu     sync.RWMutex
+	log    *slog.Logger
+	nextID uint
+	songs  map[uint]model.Song
+	keys   map[songKey]uint
+	lyrics map[uint][]model.Lyrics
+	// trash holds the deleted songs; their keys, lyrics, jobs and revisions
+	// are kept until they are purged
+	trash map[uint]model.Song
+
+	nextJobID uint
+	jobs      map[uint]model.EnrichmentJob
+	songJobs  map[uint]uint
+
+	idempotencyKeys map[string]model.IdempotencyRecord
+
+	revisions map[uint][]model.SongRevision
+}
+
+func NewMemoryStorage(log *slog.Logger) *MemoryStorage {
+	return &MemoryStorage{
+		log:       log,
+		nextID:    1,
+		songs:     make(map[uint]model.Song),
+		keys:      make(map[songKey]uint),
+		lyrics:    make(map[uint][]model.Lyrics),
+		trash:     make(map[uint]model.Song),
+		nextJobID: 1,
+		jobs:      make(map[uint]model.EnrichmentJob),
+		songJobs:  make(map[uint]uint),
+
+		idempotencyKeys: make(map[string]model.IdempotencyRecord),
+
+		revisions: make(map[uint][]model.SongRevision),
+	}
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+func (s *MemoryStorage) AddSong(ctx context.Context, song model.Song, verses []string) (uint, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := songKey{group: song.Group, name: song.Name}
+	if id, ok := s.keys[key]; ok {
+		_, deleted := s.trash[id]
+		return 0, &storage.ErrSongExists{ID: id, Deleted: deleted}
+	}
+
+	songID := s.nextID
+	s.nextID++
+
+	song.ID = songID
+	song.ReleaseDate = truncateDate(song.ReleaseDate)
+	song.InsertedAt = time.Now().UTC()
+	song.Status = storage.StatusOf(song)
+
+	lyrics := make([]model.Lyrics, 0, len(verses))
+	for i, verse := range verses {
+		lyrics = append(lyrics, model.Lyrics{
+			SongID:      songID,
+			VerseNumber: uint(i + 1),
+			Text:        verse,
+		})
+	}
+
+	s.songs[songID] = song
+	s.keys[key] = songID
+	s.lyrics[songID] = lyrics
+
+	if song.Status == model.SongStatusPendingEnrichment {
+		s.addEnrichmentJob(songID, song.InsertedAt)
+	}
+
+	s.log.Info("Song added successfully", slog.Int("song_id", int(songID)))
+
+	return songID, nil
+}
+
+func (s *MemoryStorage) DeleteSong(ctx context.Context, songID uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.songs[songID]
+	if !ok {
+		return storage.ErrSongNotFound
+	}
+
+	deletedAt := time.Now().UTC()
+	song.DeletedAt = &deletedAt
+	delete(s.songs, songID)
+	s.trash[songID] = song
+
+	s.log.Info("Song moved to trash", slog.Int("song_id", int(songID)))
+
+	return nil
+}
+
+func (s *MemoryStorage) GetSong(ctx context.Context, songID uint) (*model.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	song, ok := s.songs[songID]
+	if !ok {
+		return nil, storage.ErrSongNotFound
+	}
+
+	return &song, nil
+}
+
+func (s *MemoryStorage) GetAllSongs(
+	ctx context.Context,
+	filter model.SongFilter,
+	sort []model.SortKey,
+	after *model.SongCursor,
+	limit,
+	offset int,
+) ([]model.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if offset < 0 {
+		return nil, fmt.Errorf("OFFSET must not be negative")
+	}
+
+	if len(sort) == 0 {
+		sort = model.DefaultSongSort
+	}
+	if err := storage.ValidateSort(sort); err != nil {
+		return nil, err
+	}
+
+	matchers := buildSongMatchers(filter)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var songs []model.Song
+	for _, song := range s.songs {
+		if !s.matchSong(song, matchers) {
+			continue
+		}
+		if after != nil && compareSongs(song, cursorSong(*after), sort) <= 0 {
+			continue
+		}
+		songs = append(songs, song)
+	}
+
+	slices.SortFunc(songs, func(a, b model.Song) int {
+		return compareSongs(a, b, sort)
+	})
+
+	if limit <= 0 {
+		return songs, nil
+	}
+	return paginate(songs, limit, offset), nil
+}
+
+// CountSongs returns the number of songs matching filter.
+func (s *MemoryStorage) CountSongs(ctx context.Context, filter model.SongFilter) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	matchers := buildSongMatchers(filter)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, song := range s.songs {
+		if s.matchSong(song, matchers) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountLyrics returns the number of verses of a song.
+func (s *MemoryStorage) CountLyrics(ctx context.Context, songID uint) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.songs[songID]; !ok {
+		return 0, nil
+	}
+	return len(s.lyrics[songID]), nil
+}
+
+func (s *MemoryStorage) GetLyrics(ctx context.Context, songID uint, limit, offset int) ([]model.Lyrics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 {
+		return nil, fmt.Errorf("LIMIT must not be negative")
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("OFFSET must not be negative")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.songs[songID]; !ok {
+		return nil, nil
+	}
+	return paginate(copyLyrics(s.lyrics[songID]), limit, offset), nil
+}
+
+func (s *MemoryStorage) GetAllSongLyrics(ctx context.Context, songID uint) ([]model.Lyrics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.songs[songID]; !ok {
+		return nil, nil
+	}
+	return copyLyrics(s.lyrics[songID]), nil
+}
+
+func (s *MemoryStorage) GetLyricsForSongs(ctx context.Context, songIDs []uint) (map[uint][]model.Lyrics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lyrics := make(map[uint][]model.Lyrics, len(songIDs))
+	for _, id := range songIDs {
+		if _, ok := s.songs[id]; !ok {
+			continue
+		}
+		if verses := copyLyrics(s.lyrics[id]); len(verses) > 0 {
+			lyrics[id] = verses
+		}
+	}
+	return lyrics, nil
+}
+
+func (s *MemoryStorage) UpdateSong(ctx context.Context, songID uint, updates model.SongUpdate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if updates.Group == "" && updates.Name == "" &&
+		updates.ReleaseDate == "" && updates.Link == "" &&
+		len(updates.Verses) == 0 {
+		return fmt.Errorf("no valid fields to update")
+	}
+
+	var releaseDate time.Time
+	if updates.ReleaseDate != "" {
+		date, err := time.Parse(dateLayout, updates.ReleaseDate)
+		if err != nil {
+			return fmt.Errorf("failed to update song: %w", err)
+		}
+		releaseDate = date
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.songs[songID]
+	if !ok {
+		return storage.ErrSongNotFound
+	}
+
+	before := song
+	lyrics := s.lyrics[songID]
+	beforeLyrics := copyLyrics(lyrics)
+	verseIndex := make(map[uint]int, len(lyrics))
+	for i, lyric := range lyrics {
+		verseIndex[lyric.VerseNumber] = i
+	}
+	for verseNumber := range updates.Verses {
+		if _, ok := verseIndex[verseNumber]; !ok {
+			return fmt.Errorf("%w: verse %d", storage.ErrVerseNotFound, verseNumber)
+		}
+	}
+
+	oldKey := songKey{group: song.Group, name: song.Name}
+	if updates.Group != "" {
+		song.Group = updates.Group
+	}
+	if updates.Name != "" {
+		song.Name = updates.Name
+	}
+	if updates.ReleaseDate != "" {
+		song.ReleaseDate = releaseDate
+	}
+	if updates.Link != "" {
+		song.Link = updates.Link
+	}
+
+	newKey := songKey{group: song.Group, name: song.Name}
+	if newKey != oldKey {
+		if id, ok := s.keys[newKey]; ok {
+			_, deleted := s.trash[id]
+			return &storage.ErrSongExists{ID: id, Deleted: deleted}
+		}
+		delete(s.keys, oldKey)
+		s.keys[newKey] = songID
+	}
+	s.songs[songID] = song
+
+	for verseNumber, text := range updates.Verses {
+		lyrics[verseIndex[verseNumber]].Text = text
+	}
+	s.addClientRevision(ctx, songID, storage.DiffRevision(before, beforeLyrics, song, lyrics))
+
+	s.log.Info("Song updated successfully", slog.Int("song_id", int(songID)))
+	return nil
+}
+
+type songMatchers struct {
+	model.SongFilter
+	group *regexp.Regexp
+	name  *regexp.Regexp
+}
+
+func buildSongMatchers(filter model.SongFilter) songMatchers {
+	m := songMatchers{SongFilter: filter}
+
+	if filter.Group != "" && !filter.GroupExact {
+		m.group = ilike("%" + filter.Group + "%")
+	}
+
+	if filter.Name != "" {
+		m.name = ilike("%" + filter.Name + "%")
+	}
+
+	return m
+}
+
+// matchSong must be called with s.mu held.
+func (s *MemoryStorage) matchSong(song model.Song, m songMatchers) bool {
+	if m.group != nil && !m.group.MatchString(song.Group) {
+		return false
+	}
+	if m.GroupExact && m.Group != "" && song.Group != m.Group {
+		return false
+	}
+	if m.name != nil && !m.name.MatchString(song.Name) {
+		return false
+	}
+	if len(m.IDs) > 0 && !slices.Contains(m.IDs, song.ID) {
+		return false
+	}
+
+	releaseDate := truncateDate(song.ReleaseDate)
+	if releaseDate.IsZero() && (m.ReleaseDate != nil || m.ReleaseDateFrom != nil || m.ReleaseDateTo != nil) {
+		return false
+	}
+	if m.ReleaseDate != nil && !releaseDate.Equal(truncateDate(*m.ReleaseDate)) {
+		return false
+	}
+	if m.ReleaseDateFrom != nil && releaseDate.Before(truncateDate(*m.ReleaseDateFrom)) {
+		return false
+	}
+	if m.ReleaseDateTo != nil && releaseDate.After(truncateDate(*m.ReleaseDateTo)) {
+		return false
+	}
+	if m.InsertedAfter != nil && !song.InsertedAt.After(*m.InsertedAfter) {
+		return false
+	}
+	if m.InsertedBefore != nil && !song.InsertedAt.Before(*m.InsertedBefore) {
+		return false
+	}
+
+	if m.HasLink != nil && (song.Link != "") != *m.HasLink {
+		return false
+	}
+	if m.HasLyrics != nil && (len(s.lyrics[song.ID]) > 0) != *m.HasLyrics {
+		return false
+	}
+	return true
+}
+
+// compareSongs orders songs by the sort keys and then by ID.
+func compareSongs(a, b model.Song, sort []model.SortKey) int {
+	for _, key := range sort {
+		var c int
+		switch key.Field {
+		case model.SortByReleaseDate:
+			c = storage.SortReleaseDate(a.ReleaseDate, key.Desc).Compare(storage.SortReleaseDate(b.ReleaseDate, key.Desc))
+		case model.SortByInsertedAt:
+			c = a.InsertedAt.Compare(b.InsertedAt)
+		case model.SortByName:
+			c = strings.Compare(a.Name, b.Name)
+		case model.SortByGroup:
+			c = strings.Compare(a.Group, b.Group)
+		}
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+func cursorSong(cursor model.SongCursor) model.Song {
+	return model.Song{
+		ID:          cursor.ID,
+		Group:       cursor.Group,
+		Name:        cursor.Name,
+		ReleaseDate: truncateDate(cursor.ReleaseDate),
+		InsertedAt:  cursor.InsertedAt,
+	}
+}
+
+// ilike compiles a SQL ILIKE pattern into an equivalent regular expression:
+// '%' matches any sequence, '_' matches a single character and '\' escapes.
+func ilike(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func copyLyrics(lyrics []model.Lyrics) []model.Lyrics {
+	if len(lyrics) == 0 {
+		return nil
+	}
+	return append([]model.Lyrics(nil), lyrics...)
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
@@ -0,0 +1,15 @@
+package memory
+
+import (
+	"io"
+	"log/slog"
+	"songs_lib/internal/storage"
+	"songs_lib/internal/storage/storagetest"
+	"testing"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return NewMemoryStorage(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	})
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+func (s *MemoryStorage) GetDeletedSongs(ctx context.Context, limit, offset int) ([]model.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 {
+		return nil, fmt.Errorf("LIMIT must not be negative")
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("OFFSET must not be negative")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	songs := make([]model.Song, 0, len(s.trash))
+	for _, song := range s.trash {
+		songs = append(songs, song)
+	}
+	slices.SortFunc(songs, func(a, b model.Song) int {
+		return cmp.Or(b.DeletedAt.Compare(*a.DeletedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	return paginate(songs, limit, offset), nil
+}
+
+func (s *MemoryStorage) CountDeletedSongs(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.trash), nil
+}
+
+func (s *MemoryStorage) RestoreSong(ctx context.Context, songID uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.trash[songID]
+	if !ok {
+		return storage.ErrSongNotFound
+	}
+
+	song.DeletedAt = nil
+	delete(s.trash, songID)
+	s.songs[songID] = song
+
+	s.log.Info("Song restored", slog.Int("song_id", int(songID)))
+	return nil
+}
+
+func (s *MemoryStorage) PurgeDeletedSongs(ctx context.Context, deletedBefore time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for songID, song := range s.trash {
+		if !song.DeletedAt.Before(deletedBefore) {
+			continue
+		}
+
+		delete(s.trash, songID)
+		delete(s.keys, songKey{group: song.Group, name: song.Name})
+		delete(s.lyrics, songID)
+		delete(s.revisions, songID)
+		if jobID, ok := s.songJobs[songID]; ok {
+			delete(s.jobs, jobID)
+			delete(s.songJobs, songID)
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+)
+
+func (s *MemoryStorage) AddVerse(ctx context.Context, songID uint, position uint, text string) (uint, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.songs[songID]
+	if !ok {
+		return 0, storage.ErrSongNotFound
+	}
+
+	lyrics := s.lyrics[songID]
+	count := uint(len(lyrics))
+	if position == 0 {
+		position = count + 1
+	}
+	if position > count+1 {
+		return 0, fmt.Errorf("%w: %d, song has %d verses", storage.ErrInvalidVersePosition, position, count)
+	}
+
+	updated := make([]model.Lyrics, 0, len(lyrics)+1)
+	updated = append(updated, lyrics[:position-1]...)
+	updated = append(updated, model.Lyrics{SongID: songID, Text: text})
+	updated = append(updated, lyrics[position-1:]...)
+	s.lyrics[songID] = renumber(updated)
+	s.addClientRevision(ctx, songID, storage.DiffRevision(song, lyrics, song, updated))
+
+	s.log.Info("Verse added successfully", slog.Int("song_id", int(songID)), slog.Int("verse_number", int(position)))
+	return position, nil
+}
+
+func (s *MemoryStorage) DeleteVerse(ctx context.Context, songID uint, verseNumber uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.songs[songID]
+	if !ok {
+		return storage.ErrSongNotFound
+	}
+
+	lyrics := s.lyrics[songID]
+	if verseNumber == 0 || verseNumber > uint(len(lyrics)) {
+		return fmt.Errorf("%w: verse %d", storage.ErrVerseNotFound, verseNumber)
+	}
+
+	updated := make([]model.Lyrics, 0, len(lyrics)-1)
+	updated = append(updated, lyrics[:verseNumber-1]...)
+	updated = append(updated, lyrics[verseNumber:]...)
+	s.lyrics[songID] = renumber(updated)
+	s.addClientRevision(ctx, songID, storage.DiffRevision(song, lyrics, song, updated))
+
+	s.log.Info("Verse deleted successfully", slog.Int("song_id", int(songID)), slog.Int("verse_number", int(verseNumber)))
+	return nil
+}
+
+func (s *MemoryStorage) ReorderVerses(ctx context.Context, songID uint, order []uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song, ok := s.songs[songID]
+	if !ok {
+		return storage.ErrSongNotFound
+	}
+
+	lyrics := s.lyrics[songID]
+	if err := storage.ValidateVerseOrder(order, uint(len(lyrics))); err != nil {
+		return err
+	}
+
+	updated := make([]model.Lyrics, 0, len(lyrics))
+	for _, verseNumber := range order {
+		updated = append(updated, lyrics[verseNumber-1])
+	}
+	s.lyrics[songID] = renumber(updated)
+	s.addClientRevision(ctx, songID, storage.DiffRevision(song, lyrics, song, updated))
+
+	s.log.Info("Verses reordered successfully", slog.Int("song_id", int(songID)))
+	return nil
+}
+
+// renumber assigns consecutive verse numbers starting from 1. Lyrics are kept
+// ordered by verse number, so a verse's index is its number minus one.
+func renumber(lyrics []model.Lyrics) []model.Lyrics {
+	for i := range lyrics {
+		lyrics[i].VerseNumber = uint(i + 1)
+	}
+	return lyrics
+}
@@ -0,0 +1,257 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+const enrichmentJobColumns = `id, song_id, status, attempts, last_error, run_at, created_at, updated_at`
+
+func scanEnrichmentJob(row interface{ Scan(...any) error }) (*model.EnrichmentJob, error) {
+	job := &model.EnrichmentJob{}
+	err := row.Scan(
+		&job.ID, &job.SongID, &job.Status, &job.Attempts, &job.LastError,
+		&job.RunAt, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrEnrichmentJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *PostgresStorage) ClaimEnrichmentJob(ctx context.Context, now time.Time, lease time.Duration) (*model.EnrichmentJob, error) {
+	// SKIP LOCKED lets concurrent workers claim different jobs
+	return scanEnrichmentJob(s.db.QueryRowContext(ctx,
+		`UPDATE enrichment_jobs
+         SET status = $3, attempts = attempts + 1, run_at = $2, updated_at = $1
+         WHERE id = (
+             SELECT id FROM enrichment_jobs
+             WHERE status IN ($3, $4) AND run_at <= $1
+               AND song_id IN (SELECT id FROM songs WHERE deleted_at IS NULL)
+             ORDER BY run_at, id
+             LIMIT 1
+             FOR UPDATE SKIP LOCKED
+         )
+         RETURNING `+enrichmentJobColumns,
+		now.UTC(), now.Add(lease).UTC(),
+		model.EnrichmentJobRunning, model.EnrichmentJobPending,
+	))
+}
+
+func (s *PostgresStorage) CompleteEnrichmentJob(ctx context.Context, jobID uint, details model.SongEnrichment) error {
+	return s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var songID uint
+		err := tx.QueryRowContext(ctx,
+			`SELECT song_id FROM enrichment_jobs WHERE id = $1 FOR UPDATE`,
+			jobID,
+		).Scan(&songID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEnrichmentJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+
+		// keep whatever was set on the song in the meantime
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE songs
+             SET link = CASE WHEN COALESCE(link, '') = '' THEN $2 ELSE link END,
+                 release_date = CASE WHEN release_date <= $3 THEN $4 ELSE release_date END,
+                 status = $5
+             WHERE id = $1`,
+			songID, details.Link, time.Time{}, details.ReleaseDate, model.SongStatusReady,
+		); err != nil {
+			return err
+		}
+
+		if len(lyrics) == 0 {
+			for i, verse := range details.Verses {
+				if _, err := tx.ExecContext(ctx,
+					`INSERT INTO lyrics (song_id, verse_number, text) VALUES ($1, $2, $3)`,
+					songID, i+1, verse,
+				); err != nil {
+					return err
+				}
+			}
+		}
+		if err := recordChanges(ctx, tx, song, lyrics); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE enrichment_jobs SET status = $2, last_error = '', updated_at = $3 WHERE id = $1`,
+			jobID, model.EnrichmentJobDone, time.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		s.log.Info("Song enriched successfully", slog.Int("song_id", int(songID)))
+		return nil
+	})
+}
+
+func (s *PostgresStorage) RetryEnrichmentJob(ctx context.Context, jobID uint, lastError string, runAt time.Time) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE enrichment_jobs
+         SET status = $2, last_error = $3, run_at = $4, updated_at = $5
+         WHERE id = $1`,
+		jobID, model.EnrichmentJobPending, lastError, runAt.UTC(), time.Now().UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	return checkJobUpdated(result)
+}
+
+func (s *PostgresStorage) FailEnrichmentJob(ctx context.Context, jobID uint, lastError string) error {
+	return s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var songID uint
+		err := tx.QueryRowContext(ctx,
+			`UPDATE enrichment_jobs
+             SET status = $2, last_error = $3, updated_at = $4
+             WHERE id = $1
+             RETURNING song_id`,
+			jobID, model.EnrichmentJobFailed, lastError, time.Now().UTC(),
+		).Scan(&songID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEnrichmentJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE songs SET status = $2 WHERE id = $1`,
+			songID, model.SongStatusEnrichmentFailed,
+		)
+		return err
+	})
+}
+
+func (s *PostgresStorage) GetEnrichmentJob(ctx context.Context, songID uint) (*model.EnrichmentJob, error) {
+	return scanEnrichmentJob(s.db.QueryRowContext(ctx,
+		`SELECT `+enrichmentJobColumns+` FROM enrichment_jobs WHERE song_id = $1 AND `+songNotDeleted,
+		songID,
+	))
+}
+
+func checkJobUpdated(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrEnrichmentJobNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStorage) RefreshSong(ctx context.Context, songID uint, details model.SongEnrichment) (*model.SongDiff, error) {
+	diff, err := s.applySongDetails(ctx, songID, details, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !diff.Empty() {
+		s.log.Info("Song refreshed",
+			slog.Int("song_id", int(songID)),
+			slog.Int("fields", len(diff.Fields)),
+			slog.Int("verses", len(diff.Verses)),
+		)
+	}
+	return diff, nil
+}
+
+func (s *PostgresStorage) SetSongDetails(ctx context.Context, songID uint, details model.SongEnrichment) (*model.SongDiff, error) {
+	diff, err := s.applySongDetails(ctx, songID, details, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !diff.Empty() {
+		s.log.Info("Song details updated",
+			slog.Int("song_id", int(songID)),
+			slog.Int("fields", len(diff.Fields)),
+			slog.Int("verses", len(diff.Verses)),
+		)
+	}
+	return diff, nil
+}
+
+// applySongDetails applies what DiffSong finds changed. A refresh is refused
+// while the song is pending enrichment and marks it ready; otherwise the
+// details come from the client, as in DiffClientDetails, and the status is
+// kept.
+func (s *PostgresStorage) applySongDetails(ctx context.Context, songID uint, details model.SongEnrichment, refresh bool) (*model.SongDiff, error) {
+	var diff model.SongDiff
+
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+		if refresh && song.Status == model.SongStatusPendingEnrichment {
+			return storage.ErrSongPendingEnrichment
+		}
+
+		if refresh {
+			diff = storage.DiffSong(song, lyrics, details)
+			diff.Song.Status = model.SongStatusReady
+		} else {
+			diff = storage.DiffClientDetails(song, lyrics, details)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE songs SET link = $2, release_date = $3, status = $4, client_fields = $5 WHERE id = $1`,
+			songID, diff.Song.Link, diff.Song.ReleaseDate, diff.Song.Status, diff.Song.ClientFields,
+		); err != nil {
+			return err
+		}
+
+		if err := applyVerseChanges(ctx, tx, songID, diff.Verses); err != nil {
+			return err
+		}
+		return addRevision(ctx, tx, songID, diff)
+	}); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// songVerses returns the verses of a song inside a transaction.
+func songVerses(ctx context.Context, tx *sql.Tx, songID uint) ([]model.Lyrics, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT song_id, verse_number, text FROM lyrics
+         WHERE song_id = $1
+         ORDER BY verse_number`,
+		songID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lyrics []model.Lyrics
+	for rows.Next() {
+		var lyric model.Lyrics
+		if err := rows.Scan(&lyric.SongID, &lyric.VerseNumber, &lyric.Text); err != nil {
+			return nil, err
+		}
+		lyrics = append(lyrics, lyric)
+	}
+	return lyrics, rows.Err()
+}
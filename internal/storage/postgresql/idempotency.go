@@ -0,0 +1,89 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+func (s *PostgresStorage) ReserveIdempotencyKey(
+	ctx context.Context,
+	key,
+	fingerprint string,
+	now,
+	expiredBefore,
+	staleBefore time.Time,
+) (*model.IdempotencyRecord, error) {
+	var existing *model.IdempotencyRecord
+
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM idempotency_keys
+             WHERE created_at < $1 OR (status_code = 0 AND created_at < $2)`,
+			expiredBefore.UTC(), staleBefore.UTC(),
+		); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (key, fingerprint, created_at)
+             VALUES ($1, $2, $3)
+             ON CONFLICT (key) DO NOTHING`,
+			key, fingerprint, now.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected > 0 {
+			return err
+		}
+
+		record := &model.IdempotencyRecord{Key: key}
+		if err := tx.QueryRowContext(ctx,
+			`SELECT fingerprint, status_code, location, body, created_at
+             FROM idempotency_keys
+             WHERE key = $1`,
+			key,
+		).Scan(&record.Fingerprint, &record.StatusCode, &record.Location, &record.Body, &record.CreatedAt); err != nil {
+			return err
+		}
+		existing = record
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func (s *PostgresStorage) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, location string, body []byte) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = $2, location = $3, body = $4 WHERE key = $1`,
+		key, statusCode, location, body,
+	)
+	if err != nil {
+		return err
+	}
+	return checkIdempotencyKeyUpdated(result)
+}
+
+func (s *PostgresStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key)
+	if err != nil {
+		return err
+	}
+	return checkIdempotencyKeyUpdated(result)
+}
+
+func checkIdempotencyKeyUpdated(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrIdempotencyKeyNotFound
+	}
+	return nil
+}
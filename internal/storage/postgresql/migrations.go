@@ -0,0 +1,73 @@
+package postgresql
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// NewMigrate returns a migrate instance over the embedded migrations for the
+// database at path, a URL or a lib/pq key=value DSN. The caller is
+// responsible for closing it.
+func NewMigrate(path string) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+
+	if !strings.Contains(path, "://") {
+		// migrate only parses URLs; closing it closes the database too
+		db, err := sql.Open("postgres", path)
+		if err != nil {
+			return nil, err
+		}
+		driver, err := postgres.WithInstance(db, &postgres.Config{})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create postgres driver: %w", err)
+		}
+		m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+		if err != nil {
+			driver.Close()
+			return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+		}
+		return m, nil
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	return m, nil
+}
+
+func runMigrations(db *sql.DB) error {
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create postgres driver: %w", err)
+	}
+
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to open migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS lyrics_text_search_idx;
+
+ALTER TABLE lyrics DROP COLUMN IF EXISTS text_search;
\ No newline at end of file
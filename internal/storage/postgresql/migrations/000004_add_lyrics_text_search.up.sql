@@ -0,0 +1,5 @@
+ALTER TABLE lyrics
+    ADD COLUMN text_search tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', text)) STORED;
+
+CREATE INDEX lyrics_text_search_idx ON lyrics USING GIN (text_search);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS enrichment_jobs;
+
+ALTER TABLE songs DROP COLUMN IF EXISTS status;
@@ -0,0 +1,16 @@
+ALTER TABLE songs
+    ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'ready';
+
+CREATE TABLE enrichment_jobs(
+    id SERIAL PRIMARY KEY,
+    song_id INTEGER NOT NULL UNIQUE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (song_id) REFERENCES songs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX enrichment_jobs_run_at_idx ON enrichment_jobs (status, run_at);
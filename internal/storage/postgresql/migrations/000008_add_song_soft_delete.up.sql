@@ -0,0 +1,4 @@
+ALTER TABLE songs
+    ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX songs_deleted_at_idx ON songs (deleted_at);
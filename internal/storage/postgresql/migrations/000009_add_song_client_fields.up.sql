@@ -0,0 +1,2 @@
+ALTER TABLE songs
+    ADD COLUMN client_fields SMALLINT NOT NULL DEFAULT 0;
@@ -0,0 +1,75 @@
+package postgresql
+
+import (
+	"context"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+)
+
+// SearchLyrics ranks songs by the sum of ts_rank over their matching verses.
+// The query uses websearch_to_tsquery syntax with the 'simple' configuration,
+// so words are matched case-insensitively but without language stemming.
+func (s *PostgresStorage) SearchLyrics(
+	ctx context.Context,
+	query string,
+	limit,
+	offset int,
+) ([]model.SearchResult, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`WITH matches AS (
+             SELECT song_id, verse_number, text,
+                    ts_rank(text_search, websearch_to_tsquery('simple', $1)) AS rank
+             FROM lyrics
+             WHERE text_search @@ websearch_to_tsquery('simple', $1)
+               AND `+songNotDeleted+`
+         ),
+         ranked AS (
+             SELECT song_id, SUM(rank) AS rank
+             FROM matches
+             GROUP BY song_id
+             ORDER BY rank DESC, song_id
+             LIMIT $2 OFFSET $3
+         )
+         SELECT s.id, s.group_name, s.name, s.release_date, s.link, s.inserted_at, r.rank,
+                m.verse_number,
+                ts_headline('simple', m.text, websearch_to_tsquery('simple', $1), $4)
+         FROM ranked r
+         JOIN songs s ON s.id = r.song_id
+         JOIN matches m ON m.song_id = r.song_id
+         ORDER BY r.rank DESC, s.id, m.verse_number`,
+		query, limit, offset,
+		"StartSel="+storage.HighlightStart+", StopSel="+storage.HighlightStop+", HighlightAll=true",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []model.SearchResult
+	for rows.Next() {
+		var song model.Song
+		var rank float64
+		var lyric model.Lyrics
+		if err := rows.Scan(
+			&song.ID, &song.Group,
+			&song.Name, &song.ReleaseDate,
+			&song.Link, &song.InsertedAt,
+			&rank, &lyric.VerseNumber, &lyric.Text,
+		); err != nil {
+			return nil, err
+		}
+		lyric.SongID = song.ID
+
+		if n := len(results); n == 0 || results[n-1].Song.ID != song.ID {
+			results = append(results, model.SearchResult{Song: song, Rank: rank})
+		}
+		last := &results[len(results)-1]
+		last.Verses = append(last.Verses, lyric)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
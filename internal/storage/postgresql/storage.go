@@ -1,18 +1,19 @@
 package postgresql
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"songs_lib/internal/model"
 	"songs_lib/internal/storage"
 	"songs_lib/pkg/logger"
+	"sort"
+	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/lib/pq"
 )
 
 type PostgresStorage struct {
@@ -20,13 +21,17 @@ type PostgresStorage struct {
 	log *slog.Logger
 }
 
-func NewPostgresStorage(log *slog.Logger, path string) (*PostgresStorage, error) {
+func NewPostgresStorage(log *slog.Logger, path string, autoMigrate bool) (*PostgresStorage, error) {
 	db, err := sql.Open("postgres", path)
 	if err != nil {
 		return nil, err
 	}
-	if err := runMigrations(db); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	if autoMigrate {
+		if err := runMigrations(db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+		log.Info("Migrations applied successfully")
 	}
 
 	return &PostgresStorage{
@@ -42,45 +47,20 @@ func (s *PostgresStorage) Close() error {
 	return nil
 }
 
-func (p *PostgresStorage) BeginTx() (*sql.Tx, error) {
-	return p.db.Begin()
+func (p *PostgresStorage) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return p.db.BeginTx(ctx, nil)
 }
 
-func runMigrations(db *sql.DB) error {
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return fmt.Errorf("failed to create postgres driver: %w", err)
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://db/migrations",
-		"postgres",
-		driver,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
-	}
-
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to apply migrations: %w", err)
-	}
-
-	log.Println("Migrations applied successfully")
-	return nil
-
-}
-
-func (s *PostgresStorage) WithTransaction(fn func(tx *sql.Tx) error) error {
-	tx, err := s.db.Begin()
+func (s *PostgresStorage) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	if err := fn(tx); err != nil {
 		s.log.Error("Transaction failed", logger.Err(err))
-		err = tx.Rollback()
-		if err != nil {
-			return err
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
 		}
 
 		return err
@@ -93,22 +73,37 @@ func (s *PostgresStorage) WithTransaction(fn func(tx *sql.Tx) error) error {
 	return nil
 }
 
-func (s *PostgresStorage) AddSong(song model.Song, verses []string) (uint, error) {
+func (s *PostgresStorage) AddSong(ctx context.Context, song model.Song, verses []string) (uint, error) {
 	var songID uint
 
-	if err := s.WithTransaction(func(tx *sql.Tx) error {
-		err := tx.QueryRow(
-			`INSERT INTO songs (group_name, name, link, release_date, inserted_at) 
-             VALUES ($1, $2, $3, $4, NOW()) 
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO songs (group_name, name, link, release_date, status, client_fields, inserted_at) 
+             VALUES ($1, $2, $3, $4, $5, $6, NOW() AT TIME ZONE 'UTC')
+             ON CONFLICT (group_name, name) DO NOTHING
              RETURNING id`,
-			song.Group, song.Name, song.Link, song.ReleaseDate,
+			song.Group, song.Name, song.Link, song.ReleaseDate, storage.StatusOf(song), song.ClientFields,
 		).Scan(&songID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return existingSong(ctx, tx, song)
+		}
 		if err != nil {
 			return err
 		}
 
+		if song.Status == model.SongStatusPendingEnrichment {
+			now := time.Now().UTC()
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO enrichment_jobs (song_id, status, run_at, created_at, updated_at)
+                 VALUES ($1, $2, $3, $3, $3)`,
+				songID, model.EnrichmentJobPending, now,
+			); err != nil {
+				return err
+			}
+		}
+
 		for i, verse := range verses {
-			_, err = tx.Exec(
+			_, err = tx.ExecContext(ctx,
 				`INSERT INTO lyrics (song_id, verse_number, text) 
                  VALUES ($1, $2, $3)`,
 				songID, i+1, verse,
@@ -126,10 +121,22 @@ func (s *PostgresStorage) AddSong(song model.Song, verses []string) (uint, error
 	return songID, nil
 }
 
-func (s *PostgresStorage) DeleteSong(songID uint) error {
-	result, err := s.db.Exec(
-		`DELETE FROM songs WHERE id = $1`,
-		songID,
+// existingSong returns the ErrSongExists for a song whose insert conflicted.
+func existingSong(ctx context.Context, tx *sql.Tx, song model.Song) error {
+	exists := &storage.ErrSongExists{}
+	if err := tx.QueryRowContext(ctx,
+		`SELECT id, deleted_at IS NOT NULL FROM songs WHERE group_name = $1 AND name = $2`,
+		song.Group, song.Name,
+	).Scan(&exists.ID, &exists.Deleted); err != nil {
+		return err
+	}
+	return exists
+}
+
+func (s *PostgresStorage) DeleteSong(ctx context.Context, songID uint) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE songs SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`,
+		songID, time.Now().UTC(),
 	)
 	if err != nil {
 		return err
@@ -144,20 +151,20 @@ func (s *PostgresStorage) DeleteSong(songID uint) error {
 		return storage.ErrSongNotFound
 	}
 
-	s.log.Info("Song deleted successfully", slog.Int("song_id", int(songID)))
+	s.log.Info("Song moved to trash", slog.Int("song_id", int(songID)))
 
 	return nil
 }
 
-func (s *PostgresStorage) GetSong(songID uint) (*model.Song, error) {
+func (s *PostgresStorage) GetSong(ctx context.Context, songID uint) (*model.Song, error) {
 	song := &model.Song{}
-	err := s.db.QueryRow(
-		`SELECT id, group_name, name, link, release_date, inserted_at 
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, group_name, name, link, release_date, inserted_at, status, client_fields 
          FROM songs 
-         WHERE id = $1`,
+         WHERE id = $1 AND deleted_at IS NULL`,
 		songID,
 	).Scan(
-		&song.ID, &song.Group, &song.Name, &song.Link, &song.ReleaseDate, &song.InsertedAt,
+		&song.ID, &song.Group, &song.Name, &song.Link, &song.ReleaseDate, &song.InsertedAt, &song.Status, &song.ClientFields,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, storage.ErrSongNotFound
@@ -170,12 +177,19 @@ func (s *PostgresStorage) GetSong(songID uint) (*model.Song, error) {
 }
 
 func (s *PostgresStorage) GetAllSongs(
-	filters map[string]string,
+	ctx context.Context,
+	filter model.SongFilter,
+	sort []model.SortKey,
+	after *model.SongCursor,
 	limit,
 	offset int,
 ) ([]model.Song, error) {
-	query, args := s.buildSongQuery(filters, limit, offset)
-	rows, err := s.db.Query(query, args...)
+	query, args, err := s.buildSongQuery(filter, sort, after, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -188,6 +202,7 @@ func (s *PostgresStorage) GetAllSongs(
 			&song.ID, &song.Group,
 			&song.Name, &song.ReleaseDate,
 			&song.Link, &song.InsertedAt,
+			&song.Status, &song.ClientFields,
 		); err != nil {
 			return nil, err
 		}
@@ -201,10 +216,32 @@ func (s *PostgresStorage) GetAllSongs(
 	return songs, nil
 }
 
-func (s *PostgresStorage) GetLyrics(songID uint, limit, offset int) ([]model.Lyrics, error) {
-	rows, err := s.db.Query(
+// CountSongs returns the number of songs matching filter.
+func (s *PostgresStorage) CountSongs(ctx context.Context, filter model.SongFilter) (int, error) {
+	conditions, args := s.buildSongFilter(filter)
+
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM songs WHERE deleted_at IS NULL`+conditions,
+		args...,
+	).Scan(&count)
+	return count, err
+}
+
+// CountLyrics returns the number of verses of a song.
+func (s *PostgresStorage) CountLyrics(ctx context.Context, songID uint) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM lyrics WHERE song_id = $1 AND `+songNotDeleted,
+		songID,
+	).Scan(&count)
+	return count, err
+}
+
+func (s *PostgresStorage) GetLyrics(ctx context.Context, songID uint, limit, offset int) ([]model.Lyrics, error) {
+	rows, err := s.db.QueryContext(ctx,
 		`SELECT song_id, verse_number, text FROM lyrics 
-         WHERE song_id = $1 
+         WHERE song_id = $1 AND `+songNotDeleted+`
          ORDER BY verse_number 
          LIMIT $2 OFFSET $3`,
 		songID, limit, offset,
@@ -230,10 +267,10 @@ func (s *PostgresStorage) GetLyrics(songID uint, limit, offset int) ([]model.Lyr
 	return lyrics, nil
 }
 
-func (s *PostgresStorage) GetAllSongLyrics(songID uint) ([]model.Lyrics, error) {
-	rows, err := s.db.Query(
+func (s *PostgresStorage) GetAllSongLyrics(ctx context.Context, songID uint) ([]model.Lyrics, error) {
+	rows, err := s.db.QueryContext(ctx,
 		`SELECT song_id, verse_number, text FROM lyrics 
-		 WHERE song_id = $1
+		 WHERE song_id = $1 AND `+songNotDeleted+`
 		 ORDER BY song_id, verse_number`,
 		songID,
 	)
@@ -258,64 +295,207 @@ func (s *PostgresStorage) GetAllSongLyrics(songID uint) ([]model.Lyrics, error)
 	return lyrics, nil
 }
 
-func (s *PostgresStorage) buildSongQuery(
-	filters map[string]string,
-	limit,
-	offset int,
-) (string, []interface{}) {
-	query := `SELECT id, group_name, name, release_date, link, inserted_at 
-              FROM songs WHERE 1 = 1`
+// GetLyricsForSongs loads the verses of all given songs in a single query.
+func (s *PostgresStorage) GetLyricsForSongs(ctx context.Context, songIDs []uint) (map[uint][]model.Lyrics, error) {
+	lyrics := make(map[uint][]model.Lyrics, len(songIDs))
+	if len(songIDs) == 0 {
+		return lyrics, nil
+	}
+
+	ids := make([]int64, 0, len(songIDs))
+	for _, id := range songIDs {
+		ids = append(ids, int64(id))
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT song_id, verse_number, text FROM lyrics
+		 WHERE song_id = ANY($1) AND `+songNotDeleted+`
+		 ORDER BY song_id, verse_number`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lyric model.Lyrics
+		if err := rows.Scan(&lyric.SongID, &lyric.VerseNumber, &lyric.Text); err != nil {
+			return nil, err
+		}
+		lyrics[lyric.SongID] = append(lyrics[lyric.SongID], lyric)
+	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lyrics, nil
+}
+
+// buildSongFilter returns the WHERE conditions for filter, each prefixed
+// with AND, and their arguments numbered from $1.
+func (s *PostgresStorage) buildSongFilter(filter model.SongFilter) (string, []interface{}) {
+	var conditions string
 	var args []interface{}
 	argIndex := 1
 
-	if group, ok := filters["group"]; ok && group != "" {
-		query += fmt.Sprintf(" AND group_name ILIKE $%d", argIndex)
-		args = append(args, "%"+group+"%")
+	if filter.Group != "" {
+		if filter.GroupExact {
+			conditions += fmt.Sprintf(" AND group_name = $%d", argIndex)
+			args = append(args, filter.Group)
+		} else {
+			conditions += fmt.Sprintf(" AND group_name ILIKE $%d", argIndex)
+			args = append(args, "%"+filter.Group+"%")
+		}
 		argIndex++
 	}
 
-	if name, ok := filters["name"]; ok && name != "" {
-		query += fmt.Sprintf(" AND name ILIKE $%d", argIndex)
-		args = append(args, "%"+name+"%")
+	if filter.Name != "" {
+		conditions += fmt.Sprintf(" AND name ILIKE $%d", argIndex)
+		args = append(args, "%"+filter.Name+"%")
 		argIndex++
 	}
 
-	if releaseDate, ok := filters["release_date"]; ok && releaseDate != "" {
-		query += fmt.Sprintf(" AND release_date = $%d", argIndex)
-		args = append(args, releaseDate)
+	if len(filter.IDs) > 0 {
+		ids := make([]int64, 0, len(filter.IDs))
+		for _, id := range filter.IDs {
+			ids = append(ids, int64(id))
+		}
+		conditions += fmt.Sprintf(" AND id = ANY($%d)", argIndex)
+		args = append(args, pq.Array(ids))
 		argIndex++
 	}
 
+	if filter.ReleaseDate != nil || filter.ReleaseDateFrom != nil || filter.ReleaseDateTo != nil {
+		conditions += " AND " + storage.KnownReleaseDate
+	}
+
+	for _, cond := range []struct {
+		expr  string
+		value *time.Time
+	}{
+		{"release_date = $%d", filter.ReleaseDate},
+		{"release_date >= $%d", filter.ReleaseDateFrom},
+		{"release_date <= $%d", filter.ReleaseDateTo},
+		{"inserted_at > $%d", filter.InsertedAfter},
+		{"inserted_at < $%d", filter.InsertedBefore},
+	} {
+		if cond.value != nil {
+			// the columns have no time zone and hold UTC, while a TIMESTAMP
+			// parameter would be taken as the wall clock in its own offset
+			conditions += " AND " + fmt.Sprintf(cond.expr, argIndex)
+			args = append(args, cond.value.UTC())
+			argIndex++
+		}
+	}
+
+	if filter.HasLink != nil {
+		if *filter.HasLink {
+			conditions += " AND COALESCE(link, '') <> ''"
+		} else {
+			conditions += " AND COALESCE(link, '') = ''"
+		}
+	}
+
+	if filter.HasLyrics != nil {
+		exists := "EXISTS (SELECT 1 FROM lyrics WHERE lyrics.song_id = songs.id)"
+		if !*filter.HasLyrics {
+			exists = "NOT " + exists
+		}
+		conditions += " AND " + exists
+	}
+
+	return conditions, args
+}
+
+func (s *PostgresStorage) buildSongQuery(
+	filter model.SongFilter,
+	sort []model.SortKey,
+	after *model.SongCursor,
+	limit,
+	offset int,
+) (string, []interface{}, error) {
+	query := `SELECT id, group_name, name, release_date, link, inserted_at, status, client_fields 
+              FROM songs WHERE deleted_at IS NULL`
+
+	conditions, args := s.buildSongFilter(filter)
+	query += conditions
+	argIndex := len(args) + 1
+
+	orderBy, keyset, err := storage.BuildSongOrder(sort, after,
+		func(_ model.SortField, value interface{}) string {
+			args = append(args, value)
+			argIndex++
+			return fmt.Sprintf("$%d", argIndex-1)
+		},
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if keyset != "" {
+		query += " AND " + keyset
+	}
+
+	query += " ORDER BY " + orderBy
+
 	if limit > 0 {
-		query += fmt.Sprintf(" ORDER BY release_date LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
 		args = append(args, limit, offset)
 	}
 
-	return query, args
+	return query, args, nil
 }
 
-func (s *PostgresStorage) UpdateSong(songID uint, updates model.SongUpdate) error {
+func (s *PostgresStorage) UpdateSong(ctx context.Context, songID uint, updates model.SongUpdate) error {
 	songQuery, songArgs, err := s.buildUpdateSongQuery(songID, updates)
 	if err != nil && len(updates.Verses) == 0 {
 		return err
 	}
 
-	if songQuery != "" {
-		_, err := s.db.Exec(songQuery, songArgs...)
+	verseQueries := s.buildUpdateVerseQuery(songID, updates.Verses)
+
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		song, lyrics, err := lockSong(ctx, tx, songID)
 		if err != nil {
-			return fmt.Errorf("failed to update song: %w", err)
+			return err
 		}
-	}
 
-	verseQueries := s.buildUpdateVerseQuery(songID, updates.Verses)
+		renamed := song
+		renamed.Group = cmp.Or(updates.Group, song.Group)
+		renamed.Name = cmp.Or(updates.Name, song.Name)
+		if renamed.Group != song.Group || renamed.Name != song.Name {
+			if err := existingSong(ctx, tx, renamed); !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
 
-	for _, q := range verseQueries {
-		_, err := s.db.Exec(q.Query, q.Args...)
-		if err != nil {
-			return fmt.Errorf("failed to update verse: %w", err)
+		if songQuery != "" {
+			if _, err := tx.ExecContext(ctx, songQuery, songArgs...); err != nil {
+				return fmt.Errorf("failed to update song: %w", err)
+			}
+		}
+
+		for _, q := range verseQueries {
+			result, err := tx.ExecContext(ctx, q.Query, q.Args...)
+			if err != nil {
+				return fmt.Errorf("failed to update verse: %w", err)
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return fmt.Errorf("%w: verse %d", storage.ErrVerseNotFound, q.VerseNumber)
+			}
 		}
+		return recordClientChanges(ctx, tx, song, lyrics)
+	}); err != nil {
+		return err
 	}
+
 	s.log.Info("Song updated successfully", slog.Int("song_id", int(songID)))
 	return nil
 }
@@ -361,24 +541,25 @@ func (s *PostgresStorage) buildUpdateSongQuery(songID uint, updates model.SongUp
 	return query, args, nil
 }
 
-func (s *PostgresStorage) buildUpdateVerseQuery(songID uint, verses map[uint]string) []struct {
-	Query string
-	Args  []interface{}
-} {
-	var queries []struct {
-		Query string
-		Args  []interface{}
-	}
-
-	for verseNumber, text := range verses {
-		query := "UPDATE lyrics SET text = $1 WHERE song_id = $2 AND verse_number = $3"
-		args := []interface{}{text, songID, verseNumber}
-		queries = append(queries, struct {
-			Query string
-			Args  []interface{}
-		}{
-			Query: query,
-			Args:  args,
+type verseQuery struct {
+	VerseNumber uint
+	Query       string
+	Args        []interface{}
+}
+
+func (s *PostgresStorage) buildUpdateVerseQuery(songID uint, verses map[uint]string) []verseQuery {
+	verseNumbers := make([]uint, 0, len(verses))
+	for verseNumber := range verses {
+		verseNumbers = append(verseNumbers, verseNumber)
+	}
+	sort.Slice(verseNumbers, func(i, j int) bool { return verseNumbers[i] < verseNumbers[j] })
+
+	queries := make([]verseQuery, 0, len(verses))
+	for _, verseNumber := range verseNumbers {
+		queries = append(queries, verseQuery{
+			VerseNumber: verseNumber,
+			Query:       "UPDATE lyrics SET text = $1 WHERE song_id = $2 AND verse_number = $3",
+			Args:        []interface{}{verses[verseNumber], songID, verseNumber},
 		})
 	}
 
@@ -0,0 +1,34 @@
+package postgresql
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"songs_lib/internal/storage"
+	"songs_lib/internal/storage/storagetest"
+	"testing"
+)
+
+// TEST_STORAGE_PATH must point to a disposable database: every subtest
+// truncates the songs and lyrics tables.
+func TestPostgresStorage(t *testing.T) {
+	path := os.Getenv("TEST_STORAGE_PATH")
+	if path == "" {
+		t.Skip("TEST_STORAGE_PATH is not set")
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := NewPostgresStorage(log, path, true)
+		if err != nil {
+			t.Fatalf("NewPostgresStorage: %v", err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+
+		if _, err := s.db.Exec(`TRUNCATE songs, lyrics, enrichment_jobs, idempotency_keys, song_revisions RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("failed to truncate tables: %v", err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"songs_lib/internal/model"
+	"time"
+)
+
+// SystemActor is recorded for changes made without an actor in the context,
+// such as enrichment and scheduled refreshes.
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// WithActor returns a context whose changes are recorded in the song
+// revisions as made by actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set by WithActor, SystemActor if there is none.
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// DiffRevision compares two versions of a song and its verses, ordered by
+// number. Unlike DiffSong, every field is compared and empty values count as
+// changes, so the diff can be reverted exactly.
+func DiffRevision(before model.Song, beforeLyrics []model.Lyrics, after model.Song, afterLyrics []model.Lyrics) model.SongDiff {
+	diff := model.SongDiff{Song: after}
+
+	fields := []struct {
+		name          string
+		before, after string
+	}{
+		{"group", before.Group, after.Group},
+		{"name", before.Name, after.Name},
+		{"link", before.Link, after.Link},
+		{"release_date", formatDate(before.ReleaseDate), formatDate(after.ReleaseDate)},
+	}
+	for _, field := range fields {
+		if field.before != field.after {
+			diff.Fields = append(diff.Fields, model.FieldChange{Field: field.name, Old: field.before, New: field.after})
+		}
+	}
+
+	for i := 0; i < max(len(beforeLyrics), len(afterLyrics)); i++ {
+		change := model.VerseChange{VerseNumber: uint(i + 1)}
+		switch {
+		case i >= len(beforeLyrics):
+			change.Kind = model.ChangeAdded
+			change.New = afterLyrics[i].Text
+		case i >= len(afterLyrics):
+			change.Kind = model.ChangeRemoved
+			change.Old = beforeLyrics[i].Text
+		case beforeLyrics[i].Text != afterLyrics[i].Text:
+			change.Kind = model.ChangeModified
+			change.Old = beforeLyrics[i].Text
+			change.New = afterLyrics[i].Text
+		default:
+			continue
+		}
+		diff.Verses = append(diff.Verses, change)
+	}
+
+	return diff
+}
+
+// RevertRevisions undoes revisions, ordered newest first, on a song and its
+// verses and returns the song as it was before the last of them.
+func RevertRevisions(song model.Song, lyrics []model.Lyrics, revisions []model.SongRevision) (model.Song, []model.Lyrics, error) {
+	verses := make([]string, 0, len(lyrics))
+	for _, lyric := range lyrics {
+		verses = append(verses, lyric.Text)
+	}
+
+	for _, revision := range revisions {
+		for _, change := range revision.Fields {
+			switch change.Field {
+			case "group":
+				song.Group = change.Old
+			case "name":
+				song.Name = change.Old
+			case "link":
+				song.Link = change.Old
+			case "release_date":
+				var releaseDate time.Time
+				if change.Old != "" {
+					date, err := time.Parse(dateLayout, change.Old)
+					if err != nil {
+						return song, nil, fmt.Errorf("revision %d: invalid release date: %w", revision.Number, err)
+					}
+					releaseDate = date
+				}
+				song.ReleaseDate = releaseDate
+			}
+		}
+
+		count := len(verses)
+		for _, change := range revision.Verses {
+			switch change.Kind {
+			case model.ChangeAdded:
+				count--
+			case model.ChangeRemoved:
+				count++
+			}
+		}
+		previous := make([]string, max(count, 0))
+		copy(previous, verses)
+		for _, change := range revision.Verses {
+			if change.Kind != model.ChangeAdded && change.VerseNumber >= 1 && int(change.VerseNumber) <= len(previous) {
+				previous[change.VerseNumber-1] = change.Old
+			}
+		}
+		verses = previous
+	}
+
+	reverted := make([]model.Lyrics, 0, len(verses))
+	for i, verse := range verses {
+		reverted = append(reverted, model.Lyrics{SongID: song.ID, VerseNumber: uint(i + 1), Text: verse})
+	}
+	return song, reverted, nil
+}
+
+type revisionDiff struct {
+	Fields []model.FieldChange `json:"fields,omitempty"`
+	Verses []model.VerseChange `json:"verses,omitempty"`
+}
+
+// EncodeRevisionDiff serializes the changes of a diff for storing them in a
+// revision.
+func EncodeRevisionDiff(diff model.SongDiff) ([]byte, error) {
+	return json.Marshal(revisionDiff{Fields: diff.Fields, Verses: diff.Verses})
+}
+
+// DecodeRevisionDiff fills in the changes of a revision stored by
+// EncodeRevisionDiff.
+func DecodeRevisionDiff(data []byte, revision *model.SongRevision) error {
+	var diff revisionDiff
+	if err := json.Unmarshal(data, &diff); err != nil {
+		return fmt.Errorf("failed to decode revision %d: %w", revision.Number, err)
+	}
+	revision.Fields = diff.Fields
+	revision.Verses = diff.Verses
+	return nil
+}
+
+const dateLayout = "2006-01-02"
+
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(dateLayout)
+}
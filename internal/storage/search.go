@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"songs_lib/internal/model"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	HighlightStart = "<b>"
+	HighlightStop  = "</b>"
+)
+
+// SearchQuery is a parsed full-text query for backends without native text
+// search. It mirrors the subset of websearch_to_tsquery used by the Postgres
+// backend: plain words and "quoted phrases", all of which must occur in the
+// same verse, compared case-insensitively and without stemming.
+type SearchQuery struct {
+	terms [][]string
+}
+
+func ParseSearchQuery(q string) SearchQuery {
+	var query SearchQuery
+
+	for i, part := range strings.Split(q, `"`) {
+		words := tokenWords(part)
+		if len(words) == 0 {
+			continue
+		}
+		// odd parts are enclosed in quotes
+		if i%2 == 1 {
+			query.terms = append(query.terms, words)
+			continue
+		}
+		for _, word := range words {
+			query.terms = append(query.terms, []string{word})
+		}
+	}
+
+	return query
+}
+
+func (q SearchQuery) Empty() bool {
+	return len(q.terms) == 0
+}
+
+// Words returns every distinct word of the query.
+func (q SearchQuery) Words() []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, term := range q.terms {
+		for _, word := range term {
+			if !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+	}
+	return words
+}
+
+// Match returns the number of term occurrences in text, or 0 if some term is
+// missing, together with the text where matched words are highlighted.
+func (q SearchQuery) Match(text string) (float64, string) {
+	if q.Empty() {
+		return 0, text
+	}
+
+	tokens := tokenize(text)
+	highlighted := make([]bool, len(tokens))
+
+	var total int
+	for _, term := range q.terms {
+		found := 0
+		for i := 0; i+len(term) <= len(tokens); i++ {
+			if !tokensEqual(tokens[i:i+len(term)], term) {
+				continue
+			}
+			found++
+			for j := range term {
+				highlighted[i+j] = true
+			}
+		}
+		if found == 0 {
+			return 0, text
+		}
+		total += found
+	}
+
+	var b strings.Builder
+	last := 0
+	for i, token := range tokens {
+		if !highlighted[i] {
+			continue
+		}
+		b.WriteString(text[last:token.start])
+		b.WriteString(HighlightStart)
+		b.WriteString(text[token.start:token.end])
+		b.WriteString(HighlightStop)
+		last = token.end
+	}
+	b.WriteString(text[last:])
+
+	return float64(total), b.String()
+}
+
+// SearchSongs matches every verse against q and groups the matches by song,
+// ordered by rank and then by song ID. Verses of a result are highlighted.
+func SearchSongs(q SearchQuery, songs []model.Song, lyrics map[uint][]model.Lyrics) []model.SearchResult {
+	var results []model.SearchResult
+	for _, song := range songs {
+		result := model.SearchResult{Song: song}
+		for _, lyric := range lyrics[song.ID] {
+			rank, highlight := q.Match(lyric.Text)
+			if rank == 0 {
+				continue
+			}
+			result.Rank += rank
+			lyric.Text = highlight
+			result.Verses = append(result.Verses, lyric)
+		}
+		if len(result.Verses) > 0 {
+			sort.Slice(result.Verses, func(i, j int) bool {
+				return result.Verses[i].VerseNumber < result.Verses[j].VerseNumber
+			})
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Song.ID < results[j].Song.ID
+	})
+	return results
+}
+
+type token struct {
+	word       string
+	start, end int
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			tokens = append(tokens, token{word: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{word: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+func tokenWords(text string) []string {
+	tokens := tokenize(text)
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		words = append(words, token.word)
+	}
+	return words
+}
+
+func tokensEqual(tokens []token, words []string) bool {
+	for i, word := range words {
+		if tokens[i].word != word {
+			return false
+		}
+	}
+	return true
+}
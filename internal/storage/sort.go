@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"songs_lib/internal/model"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSort = errors.New("invalid sort")
+
+// SortByID is passed to bind for the ID tie-breaker of the keyset condition.
+const SortByID model.SortField = "id"
+
+// songSortColumns whitelists the fields the library can be ordered by.
+var songSortColumns = map[model.SortField]string{
+	model.SortByReleaseDate: "release_date",
+	model.SortByInsertedAt:  "inserted_at",
+	model.SortByName:        "name",
+	model.SortByGroup:       "group_name",
+}
+
+// KnownReleaseDate is an SQL condition that excludes the songs whose release
+// date is not known yet. They are stored with the zero date until enrichment
+// fills it in.
+const KnownReleaseDate = "release_date > '0001-01-01'"
+
+// lastReleaseDate stands in for an unknown release date when sorting in
+// ascending order, so such songs come last in both directions.
+var lastReleaseDate = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// SortReleaseDate returns the value a release date is ordered by.
+func SortReleaseDate(date time.Time, desc bool) time.Time {
+	if date.IsZero() && !desc {
+		return lastReleaseDate
+	}
+	return date
+}
+
+// sortColumn returns the SQL expression a key orders by, matching
+// SortReleaseDate for the release date.
+func sortColumn(key model.SortKey) string {
+	if key.Field == model.SortByReleaseDate && !key.Desc {
+		return "CASE WHEN " + KnownReleaseDate + " THEN release_date ELSE '" + lastReleaseDate.Format("2006-01-02") + "' END"
+	}
+	return songSortColumns[key.Field]
+}
+
+// sortValue returns the cursor value a key is compared with.
+func sortValue(cursor model.SongCursor, key model.SortKey) interface{} {
+	if key.Field == model.SortByReleaseDate {
+		return SortReleaseDate(cursor.ReleaseDate, key.Desc)
+	}
+	return CursorValue(cursor, key.Field)
+}
+
+// ValidateSort checks that every key refers to a sortable field.
+func ValidateSort(sort []model.SortKey) error {
+	seen := make(map[model.SortField]bool, len(sort))
+	for _, key := range sort {
+		if _, ok := songSortColumns[key.Field]; !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidSort, key.Field)
+		}
+		if seen[key.Field] {
+			return fmt.Errorf("%w: duplicate field %q", ErrInvalidSort, key.Field)
+		}
+		seen[key.Field] = true
+	}
+	return nil
+}
+
+// BuildSongOrder returns the ORDER BY expression for sort followed by the ID
+// and, if after is set, a keyset condition that selects the songs following
+// the cursor in that order. bind is called for every cursor value in order of
+// appearance and must return its SQL placeholder.
+func BuildSongOrder(
+	sort []model.SortKey,
+	after *model.SongCursor,
+	bind func(field model.SortField, value interface{}) string,
+) (string, string, error) {
+	if len(sort) == 0 {
+		sort = model.DefaultSongSort
+	}
+	if err := ValidateSort(sort); err != nil {
+		return "", "", err
+	}
+
+	order := make([]string, 0, len(sort)+1)
+	for _, key := range sort {
+		column := sortColumn(key)
+		if key.Desc {
+			column += " DESC"
+		}
+		order = append(order, column)
+	}
+	order = append(order, "id")
+
+	if after == nil {
+		return strings.Join(order, ", "), "", nil
+	}
+
+	// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... OR (k1 = v1 AND ... AND id > v)
+	// bind is called once per placeholder, so positional "?" args line up.
+	alternatives := make([]string, 0, len(sort)+1)
+	for i := 0; i <= len(sort); i++ {
+		condition := make([]string, 0, i+1)
+		for _, key := range sort[:i] {
+			condition = append(condition, sortColumn(key)+" = "+bind(key.Field, sortValue(*after, key)))
+		}
+
+		if i == len(sort) {
+			condition = append(condition, "id > "+bind(SortByID, after.ID))
+		} else {
+			key := sort[i]
+			op := ">"
+			if key.Desc {
+				op = "<"
+			}
+			condition = append(condition, sortColumn(key)+" "+op+" "+bind(key.Field, sortValue(*after, key)))
+		}
+		alternatives = append(alternatives, "("+strings.Join(condition, " AND ")+")")
+	}
+
+	return strings.Join(order, ", "), "(" + strings.Join(alternatives, " OR ") + ")", nil
+}
+
+// CursorValue returns the cursor value of a sort field.
+func CursorValue(cursor model.SongCursor, field model.SortField) interface{} {
+	switch field {
+	case model.SortByReleaseDate:
+		return cursor.ReleaseDate
+	case model.SortByInsertedAt:
+		return cursor.InsertedAt
+	case model.SortByName:
+		return cursor.Name
+	case model.SortByGroup:
+		return cursor.Group
+	default:
+		return cursor.ID
+	}
+}
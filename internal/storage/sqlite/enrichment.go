@@ -0,0 +1,258 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+const enrichmentJobColumns = `id, song_id, status, attempts, last_error, run_at, created_at, updated_at`
+
+func scanEnrichmentJob(row interface{ Scan(...any) error }) (*model.EnrichmentJob, error) {
+	job := &model.EnrichmentJob{}
+	err := row.Scan(
+		&job.ID, &job.SongID, &job.Status, &job.Attempts, &job.LastError,
+		&job.RunAt, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrEnrichmentJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ClaimEnrichmentJob needs no row locks: a single statement is atomic and
+// the connection pool is limited to one connection.
+func (s *SQLiteStorage) ClaimEnrichmentJob(ctx context.Context, now time.Time, lease time.Duration) (*model.EnrichmentJob, error) {
+	return scanEnrichmentJob(s.db.QueryRowContext(ctx,
+		`UPDATE enrichment_jobs
+         SET status = ?, attempts = attempts + 1, run_at = ?, updated_at = ?
+         WHERE id = (
+             SELECT id FROM enrichment_jobs
+             WHERE status IN (?, ?) AND run_at <= ?
+               AND song_id IN (SELECT id FROM songs WHERE deleted_at IS NULL)
+             ORDER BY run_at, id
+             LIMIT 1
+         )
+         RETURNING `+enrichmentJobColumns,
+		model.EnrichmentJobRunning, formatTimestamp(now.Add(lease)), formatTimestamp(now),
+		model.EnrichmentJobPending, model.EnrichmentJobRunning, formatTimestamp(now),
+	))
+}
+
+func (s *SQLiteStorage) CompleteEnrichmentJob(ctx context.Context, jobID uint, details model.SongEnrichment) error {
+	return s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var songID uint
+		err := tx.QueryRowContext(ctx,
+			`SELECT song_id FROM enrichment_jobs WHERE id = ?`,
+			jobID,
+		).Scan(&songID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEnrichmentJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+
+		// keep whatever was set on the song in the meantime
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE songs
+             SET link = CASE WHEN COALESCE(link, '') = '' THEN ? ELSE link END,
+                 release_date = CASE WHEN release_date <= ? THEN ? ELSE release_date END,
+                 status = ?
+             WHERE id = ?`,
+			details.Link, time.Time{}.Format(dateLayout), details.ReleaseDate.Format(dateLayout),
+			model.SongStatusReady, songID,
+		); err != nil {
+			return err
+		}
+
+		if len(lyrics) == 0 {
+			for i, verse := range details.Verses {
+				if _, err := tx.ExecContext(ctx,
+					`INSERT INTO lyrics (song_id, verse_number, text) VALUES (?, ?, ?)`,
+					songID, i+1, verse,
+				); err != nil {
+					return err
+				}
+			}
+		}
+		if err := recordChanges(ctx, tx, song, lyrics); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE enrichment_jobs SET status = ?, last_error = '', updated_at = ? WHERE id = ?`,
+			model.EnrichmentJobDone, formatTimestamp(time.Now()), jobID,
+		)
+		if err != nil {
+			return err
+		}
+
+		s.log.Info("Song enriched successfully", slog.Int("song_id", int(songID)))
+		return nil
+	})
+}
+
+func (s *SQLiteStorage) RetryEnrichmentJob(ctx context.Context, jobID uint, lastError string, runAt time.Time) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE enrichment_jobs
+         SET status = ?, last_error = ?, run_at = ?, updated_at = ?
+         WHERE id = ?`,
+		model.EnrichmentJobPending, lastError, formatTimestamp(runAt), formatTimestamp(time.Now()), jobID,
+	)
+	if err != nil {
+		return err
+	}
+	return checkJobUpdated(result)
+}
+
+func (s *SQLiteStorage) FailEnrichmentJob(ctx context.Context, jobID uint, lastError string) error {
+	return s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var songID uint
+		err := tx.QueryRowContext(ctx,
+			`UPDATE enrichment_jobs
+             SET status = ?, last_error = ?, updated_at = ?
+             WHERE id = ?
+             RETURNING song_id`,
+			model.EnrichmentJobFailed, lastError, formatTimestamp(time.Now()), jobID,
+		).Scan(&songID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEnrichmentJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE songs SET status = ? WHERE id = ?`,
+			model.SongStatusEnrichmentFailed, songID,
+		)
+		return err
+	})
+}
+
+func (s *SQLiteStorage) GetEnrichmentJob(ctx context.Context, songID uint) (*model.EnrichmentJob, error) {
+	return scanEnrichmentJob(s.db.QueryRowContext(ctx,
+		`SELECT `+enrichmentJobColumns+` FROM enrichment_jobs WHERE song_id = ? AND `+songNotDeleted,
+		songID,
+	))
+}
+
+func checkJobUpdated(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrEnrichmentJobNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) RefreshSong(ctx context.Context, songID uint, details model.SongEnrichment) (*model.SongDiff, error) {
+	diff, err := s.applySongDetails(ctx, songID, details, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !diff.Empty() {
+		s.log.Info("Song refreshed",
+			slog.Int("song_id", int(songID)),
+			slog.Int("fields", len(diff.Fields)),
+			slog.Int("verses", len(diff.Verses)),
+		)
+	}
+	return diff, nil
+}
+
+func (s *SQLiteStorage) SetSongDetails(ctx context.Context, songID uint, details model.SongEnrichment) (*model.SongDiff, error) {
+	diff, err := s.applySongDetails(ctx, songID, details, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !diff.Empty() {
+		s.log.Info("Song details updated",
+			slog.Int("song_id", int(songID)),
+			slog.Int("fields", len(diff.Fields)),
+			slog.Int("verses", len(diff.Verses)),
+		)
+	}
+	return diff, nil
+}
+
+// applySongDetails applies what DiffSong finds changed. A refresh is refused
+// while the song is pending enrichment and marks it ready; otherwise the
+// details come from the client, as in DiffClientDetails, and the status is
+// kept.
+func (s *SQLiteStorage) applySongDetails(ctx context.Context, songID uint, details model.SongEnrichment, refresh bool) (*model.SongDiff, error) {
+	var diff model.SongDiff
+
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+		if refresh && song.Status == model.SongStatusPendingEnrichment {
+			return storage.ErrSongPendingEnrichment
+		}
+
+		if refresh {
+			diff = storage.DiffSong(song, lyrics, details)
+			diff.Song.Status = model.SongStatusReady
+		} else {
+			diff = storage.DiffClientDetails(song, lyrics, details)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE songs SET link = ?, release_date = ?, status = ?, client_fields = ? WHERE id = ?`,
+			diff.Song.Link, diff.Song.ReleaseDate.Format(dateLayout), diff.Song.Status, diff.Song.ClientFields, songID,
+		); err != nil {
+			return err
+		}
+
+		if err := applyVerseChanges(ctx, tx, songID, diff.Verses); err != nil {
+			return err
+		}
+		return addRevision(ctx, tx, songID, diff)
+	}); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// songVerses returns the verses of a song inside a transaction.
+func songVerses(ctx context.Context, tx *sql.Tx, songID uint) ([]model.Lyrics, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT song_id, verse_number, text FROM lyrics
+         WHERE song_id = ?
+         ORDER BY verse_number`,
+		songID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lyrics []model.Lyrics
+	for rows.Next() {
+		var lyric model.Lyrics
+		if err := rows.Scan(&lyric.SongID, &lyric.VerseNumber, &lyric.Text); err != nil {
+			return nil, err
+		}
+		lyrics = append(lyrics, lyric)
+	}
+	return lyrics, rows.Err()
+}
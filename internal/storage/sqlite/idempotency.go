@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+func (s *SQLiteStorage) ReserveIdempotencyKey(
+	ctx context.Context,
+	key,
+	fingerprint string,
+	now,
+	expiredBefore,
+	staleBefore time.Time,
+) (*model.IdempotencyRecord, error) {
+	var existing *model.IdempotencyRecord
+
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM idempotency_keys
+             WHERE created_at < ? OR (status_code = 0 AND created_at < ?)`,
+			formatTimestamp(expiredBefore), formatTimestamp(staleBefore),
+		); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (key, fingerprint, created_at)
+             VALUES (?, ?, ?)
+             ON CONFLICT (key) DO NOTHING`,
+			key, fingerprint, formatTimestamp(now),
+		)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected > 0 {
+			return err
+		}
+
+		record := &model.IdempotencyRecord{Key: key}
+		if err := tx.QueryRowContext(ctx,
+			`SELECT fingerprint, status_code, location, body, created_at
+             FROM idempotency_keys
+             WHERE key = ?`,
+			key,
+		).Scan(&record.Fingerprint, &record.StatusCode, &record.Location, &record.Body, &record.CreatedAt); err != nil {
+			return err
+		}
+		existing = record
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func (s *SQLiteStorage) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, location string, body []byte) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = ?, location = ?, body = ? WHERE key = ?`,
+		statusCode, location, body, key,
+	)
+	if err != nil {
+		return err
+	}
+	return checkIdempotencyKeyUpdated(result)
+}
+
+func (s *SQLiteStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ?`, key)
+	if err != nil {
+		return err
+	}
+	return checkIdempotencyKeyUpdated(result)
+}
+
+func checkIdempotencyKeyUpdated(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrIdempotencyKeyNotFound
+	}
+	return nil
+}
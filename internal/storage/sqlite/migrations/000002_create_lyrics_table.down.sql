@@ -0,0 +1 @@
+DROP TABLE IF EXISTS lyrics;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE song_revisions(
+    song_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    diff TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (song_id, revision),
+    FOREIGN KEY (song_id) REFERENCES songs(id) ON DELETE CASCADE
+);
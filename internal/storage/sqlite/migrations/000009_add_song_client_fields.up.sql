@@ -0,0 +1,2 @@
+ALTER TABLE songs
+    ADD COLUMN client_fields INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,239 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+// lockSong returns a song with its verses. Transactions are serialized by the
+// single database connection.
+func lockSong(ctx context.Context, tx *sql.Tx, songID uint) (model.Song, []model.Lyrics, error) {
+	var song model.Song
+	var link sql.NullString
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, group_name, name, link, release_date, inserted_at, status, client_fields
+         FROM songs
+         WHERE id = ? AND deleted_at IS NULL`,
+		songID,
+	).Scan(&song.ID, &song.Group, &song.Name, &link, &song.ReleaseDate, &song.InsertedAt, &song.Status, &song.ClientFields)
+	if errors.Is(err, sql.ErrNoRows) {
+		return song, nil, storage.ErrSongNotFound
+	}
+	if err != nil {
+		return song, nil, err
+	}
+	song.Link = link.String
+
+	lyrics, err := songVerses(ctx, tx, songID)
+	if err != nil {
+		return song, nil, err
+	}
+	return song, lyrics, nil
+}
+
+// recordChanges records the difference between the song as it was locked by
+// lockSong and its current state as a revision.
+func recordChanges(ctx context.Context, tx *sql.Tx, before model.Song, beforeLyrics []model.Lyrics) error {
+	after, afterLyrics, err := lockSong(ctx, tx, before.ID)
+	if err != nil {
+		return err
+	}
+	return addRevision(ctx, tx, before.ID, storage.DiffRevision(before, beforeLyrics, after, afterLyrics))
+}
+
+// recordClientChanges is recordChanges for an edit of the client, which also
+// marks the details it changed as client fields.
+func recordClientChanges(ctx context.Context, tx *sql.Tx, before model.Song, beforeLyrics []model.Lyrics) error {
+	after, afterLyrics, err := lockSong(ctx, tx, before.ID)
+	if err != nil {
+		return err
+	}
+	diff := storage.DiffRevision(before, beforeLyrics, after, afterLyrics)
+	if err := markClientFields(ctx, tx, before.ID, storage.ChangedFields(diff)); err != nil {
+		return err
+	}
+	return addRevision(ctx, tx, before.ID, diff)
+}
+
+// markClientFields adds fields to the client fields of a song.
+func markClientFields(ctx context.Context, tx *sql.Tx, songID uint, fields model.SongFields) error {
+	if fields == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE songs SET client_fields = client_fields | ? WHERE id = ?`,
+		fields, songID,
+	); err != nil {
+		return fmt.Errorf("failed to update client fields: %w", err)
+	}
+	return nil
+}
+
+// addRevision records the changes of a diff as the next revision of the
+// song, which must be locked.
+func addRevision(ctx context.Context, tx *sql.Tx, songID uint, diff model.SongDiff) error {
+	if diff.Empty() {
+		return nil
+	}
+
+	data, err := storage.EncodeRevisionDiff(diff)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO song_revisions (song_id, revision, actor, diff, created_at)
+         SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ?
+         FROM song_revisions
+         WHERE song_id = ?`,
+		songID, storage.ActorFrom(ctx), string(data), formatTimestamp(time.Now()), songID,
+	); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// applyVerseChanges writes the verse changes of a diff.
+func applyVerseChanges(ctx context.Context, tx *sql.Tx, songID uint, changes []model.VerseChange) error {
+	for _, change := range changes {
+		var query string
+		var args []interface{}
+		switch change.Kind {
+		case model.ChangeAdded:
+			query = `INSERT INTO lyrics (song_id, verse_number, text) VALUES (?, ?, ?)`
+			args = []interface{}{songID, change.VerseNumber, change.New}
+		case model.ChangeModified:
+			query = `UPDATE lyrics SET text = ? WHERE song_id = ? AND verse_number = ?`
+			args = []interface{}{change.New, songID, change.VerseNumber}
+		case model.ChangeRemoved:
+			query = `DELETE FROM lyrics WHERE song_id = ? AND verse_number = ?`
+			args = []interface{}{songID, change.VerseNumber}
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) GetSongRevisions(ctx context.Context, songID uint, limit, offset int) ([]model.SongRevision, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM songs WHERE id = ? AND deleted_at IS NULL)`,
+		songID,
+	).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, storage.ErrSongNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT song_id, revision, actor, diff, created_at
+         FROM song_revisions
+         WHERE song_id = ?
+         ORDER BY revision DESC
+         LIMIT ? OFFSET ?`,
+		songID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanRevisions(rows)
+}
+
+func (s *SQLiteStorage) CountSongRevisions(ctx context.Context, songID uint) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM song_revisions WHERE song_id = ?`,
+		songID,
+	).Scan(&count)
+	return count, err
+}
+
+func (s *SQLiteStorage) RevertSong(ctx context.Context, songID uint, revision uint) (*model.SongDiff, error) {
+	var diff model.SongDiff
+
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx,
+			`SELECT song_id, revision, actor, diff, created_at
+             FROM song_revisions
+             WHERE song_id = ? AND revision >= ?
+             ORDER BY revision DESC`,
+			songID, revision,
+		)
+		if err != nil {
+			return err
+		}
+		undone, err := scanRevisions(rows)
+		if err != nil {
+			return err
+		}
+		if len(undone) == 0 || undone[len(undone)-1].Number != revision {
+			return fmt.Errorf("%w: %d", storage.ErrRevisionNotFound, revision)
+		}
+
+		reverted, revertedLyrics, err := storage.RevertRevisions(song, lyrics, undone)
+		if err != nil {
+			return err
+		}
+		diff = storage.DiffRevision(song, lyrics, reverted, revertedLyrics)
+
+		if reverted.Group != song.Group || reverted.Name != song.Name {
+			if err := existingSong(ctx, tx, reverted); !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE songs SET group_name = ?, name = ?, link = ?, release_date = ? WHERE id = ?`,
+			reverted.Group, reverted.Name, reverted.Link, reverted.ReleaseDate.Format(dateLayout), songID,
+		); err != nil {
+			return fmt.Errorf("failed to update song: %w", err)
+		}
+		if err := applyVerseChanges(ctx, tx, songID, diff.Verses); err != nil {
+			return err
+		}
+		if err := markClientFields(ctx, tx, songID, storage.ChangedFields(diff)); err != nil {
+			return err
+		}
+		return addRevision(ctx, tx, songID, diff)
+	}); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Song reverted",
+		slog.Int("song_id", int(songID)),
+		slog.Int("revision", int(revision)),
+	)
+	return &diff, nil
+}
+
+func scanRevisions(rows *sql.Rows) ([]model.SongRevision, error) {
+	defer rows.Close()
+
+	var revisions []model.SongRevision
+	for rows.Next() {
+		var revision model.SongRevision
+		var data []byte
+		if err := rows.Scan(&revision.SongID, &revision.Number, &revision.Actor, &data, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := storage.DecodeRevisionDiff(data, &revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
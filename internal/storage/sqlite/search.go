@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"strings"
+	"unicode/utf8"
+)
+
+// SearchLyrics narrows candidates down with LIKE and ranks them with the
+// shared storage.SearchQuery matcher, since SQLite has no tsvector support.
+func (s *SQLiteStorage) SearchLyrics(
+	ctx context.Context,
+	query string,
+	limit,
+	offset int,
+) ([]model.SearchResult, error) {
+	q := storage.ParseSearchQuery(query)
+	if q.Empty() {
+		return nil, nil
+	}
+
+	sqlQuery := `SELECT s.id, s.group_name, s.name, s.release_date, s.link, s.inserted_at,
+                        l.verse_number, l.text
+                 FROM lyrics l
+                 JOIN songs s ON s.id = l.song_id
+                 WHERE s.deleted_at IS NULL`
+	var args []interface{}
+	for _, word := range q.Words() {
+		// LIKE folds case for ASCII only, other words are left to the matcher
+		if !isASCII(word) {
+			continue
+		}
+		sqlQuery += ` AND l.text LIKE ? ESCAPE '\'`
+		args = append(args, "%"+escapeLike(word)+"%")
+	}
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []model.Song
+	lyrics := make(map[uint][]model.Lyrics)
+	for rows.Next() {
+		var song model.Song
+		var link sql.NullString
+		var lyric model.Lyrics
+		if err := rows.Scan(
+			&song.ID, &song.Group,
+			&song.Name, &song.ReleaseDate,
+			&link, &song.InsertedAt,
+			&lyric.VerseNumber, &lyric.Text,
+		); err != nil {
+			return nil, err
+		}
+		song.Link = link.String
+		lyric.SongID = song.ID
+
+		if _, ok := lyrics[song.ID]; !ok {
+			songs = append(songs, song)
+		}
+		lyrics[song.ID] = append(lyrics[song.ID], lyric)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := storage.SearchSongs(q, songs, lyrics)
+	if offset >= len(results) {
+		return nil, nil
+	}
+	results = results[offset:]
+	if limit < len(results) {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func isASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
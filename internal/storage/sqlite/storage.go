@@ -0,0 +1,562 @@
+package sqlite
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"songs_lib/pkg/logger"
+	"sort"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// timestampLayout matches CURRENT_TIMESTAMP, which is always UTC
+	timestampLayout = "2006-01-02 15:04:05"
+)
+
+type SQLiteStorage struct {
+	db  *sql.DB
+	log *slog.Logger
+}
+
+// NewSQLiteStorage opens the database at path, which is either a bare file
+// name or a sqlite:// URL, e.g. sqlite://songs.db or sqlite:///var/lib/songs.db.
+func NewSQLiteStorage(log *slog.Logger, path string, autoMigrate bool) (*SQLiteStorage, error) {
+	db, err := sql.Open("sqlite", dsn(path))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; serializing connections avoids SQLITE_BUSY
+	// and keeps :memory: databases shared across queries.
+	db.SetMaxOpenConns(1)
+
+	if autoMigrate {
+		if err := runMigrations(db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+		log.Info("Migrations applied successfully")
+	}
+
+	return &SQLiteStorage{
+		db:  db,
+		log: log,
+	}, nil
+}
+
+func dsn(path string) string {
+	path = strings.TrimPrefix(path, "sqlite://")
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
+
+func (s *SQLiteStorage) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		s.log.Error("Transaction failed", logger.Err(err))
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStorage) AddSong(ctx context.Context, song model.Song, verses []string) (uint, error) {
+	var songID uint
+
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO songs (group_name, name, link, release_date, status, client_fields, inserted_at)
+             VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+             ON CONFLICT (group_name, name) DO NOTHING
+             RETURNING id`,
+			song.Group, song.Name, song.Link, song.ReleaseDate.Format(dateLayout), storage.StatusOf(song), song.ClientFields,
+		).Scan(&songID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return existingSong(ctx, tx, song)
+		}
+		if err != nil {
+			return err
+		}
+
+		if song.Status == model.SongStatusPendingEnrichment {
+			now := formatTimestamp(time.Now())
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO enrichment_jobs (song_id, status, run_at, created_at, updated_at)
+                 VALUES (?, ?, ?, ?, ?)`,
+				songID, model.EnrichmentJobPending, now, now, now,
+			); err != nil {
+				return err
+			}
+		}
+
+		for i, verse := range verses {
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO lyrics (song_id, verse_number, text)
+                 VALUES (?, ?, ?)`,
+				songID, i+1, verse,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	s.log.Info("Song added successfully", slog.Int("song_id", int(songID)))
+
+	return songID, nil
+}
+
+// existingSong returns the ErrSongExists for a song whose insert conflicted.
+func existingSong(ctx context.Context, tx *sql.Tx, song model.Song) error {
+	exists := &storage.ErrSongExists{}
+	if err := tx.QueryRowContext(ctx,
+		`SELECT id, deleted_at IS NOT NULL FROM songs WHERE group_name = ? AND name = ?`,
+		song.Group, song.Name,
+	).Scan(&exists.ID, &exists.Deleted); err != nil {
+		return err
+	}
+	return exists
+}
+
+func (s *SQLiteStorage) DeleteSong(ctx context.Context, songID uint) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE songs SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		formatTimestamp(time.Now()), songID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrSongNotFound
+	}
+
+	s.log.Info("Song moved to trash", slog.Int("song_id", int(songID)))
+
+	return nil
+}
+
+func (s *SQLiteStorage) GetSong(ctx context.Context, songID uint) (*model.Song, error) {
+	song := &model.Song{}
+	var link sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, group_name, name, link, release_date, inserted_at, status, client_fields
+         FROM songs
+         WHERE id = ? AND deleted_at IS NULL`,
+		songID,
+	).Scan(
+		&song.ID, &song.Group, &song.Name, &link, &song.ReleaseDate, &song.InsertedAt, &song.Status, &song.ClientFields,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrSongNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	song.Link = link.String
+
+	return song, nil
+}
+
+func (s *SQLiteStorage) GetAllSongs(
+	ctx context.Context,
+	filter model.SongFilter,
+	sort []model.SortKey,
+	after *model.SongCursor,
+	limit,
+	offset int,
+) ([]model.Song, error) {
+	query, args, err := s.buildSongQuery(filter, sort, after, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []model.Song
+	for rows.Next() {
+		var song model.Song
+		var link sql.NullString
+		if err := rows.Scan(
+			&song.ID, &song.Group,
+			&song.Name, &song.ReleaseDate,
+			&link, &song.InsertedAt,
+			&song.Status, &song.ClientFields,
+		); err != nil {
+			return nil, err
+		}
+		song.Link = link.String
+		songs = append(songs, song)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return songs, nil
+}
+
+// CountSongs returns the number of songs matching filter.
+func (s *SQLiteStorage) CountSongs(ctx context.Context, filter model.SongFilter) (int, error) {
+	conditions, args := s.buildSongFilter(filter)
+
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM songs WHERE deleted_at IS NULL`+conditions,
+		args...,
+	).Scan(&count)
+	return count, err
+}
+
+// CountLyrics returns the number of verses of a song.
+func (s *SQLiteStorage) CountLyrics(ctx context.Context, songID uint) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM lyrics WHERE song_id = ? AND `+songNotDeleted,
+		songID,
+	).Scan(&count)
+	return count, err
+}
+
+func (s *SQLiteStorage) GetLyrics(ctx context.Context, songID uint, limit, offset int) ([]model.Lyrics, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("LIMIT must not be negative")
+	}
+
+	return s.queryLyrics(
+		ctx,
+		`SELECT song_id, verse_number, text FROM lyrics
+         WHERE song_id = ? AND `+songNotDeleted+`
+         ORDER BY verse_number
+         LIMIT ? OFFSET ?`,
+		songID, limit, offset,
+	)
+}
+
+func (s *SQLiteStorage) GetAllSongLyrics(ctx context.Context, songID uint) ([]model.Lyrics, error) {
+	return s.queryLyrics(
+		ctx,
+		`SELECT song_id, verse_number, text FROM lyrics
+         WHERE song_id = ? AND `+songNotDeleted+`
+         ORDER BY song_id, verse_number`,
+		songID,
+	)
+}
+
+// GetLyricsForSongs loads the verses of all given songs in a single query.
+func (s *SQLiteStorage) GetLyricsForSongs(ctx context.Context, songIDs []uint) (map[uint][]model.Lyrics, error) {
+	lyrics := make(map[uint][]model.Lyrics, len(songIDs))
+	if len(songIDs) == 0 {
+		return lyrics, nil
+	}
+
+	placeholders := make([]string, 0, len(songIDs))
+	args := make([]interface{}, 0, len(songIDs))
+	for _, id := range songIDs {
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+
+	rows, err := s.queryLyrics(ctx,
+		`SELECT song_id, verse_number, text FROM lyrics
+         WHERE song_id IN (`+strings.Join(placeholders, ", ")+`) AND `+songNotDeleted+`
+         ORDER BY song_id, verse_number`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lyric := range rows {
+		lyrics[lyric.SongID] = append(lyrics[lyric.SongID], lyric)
+	}
+	return lyrics, nil
+}
+
+func (s *SQLiteStorage) queryLyrics(ctx context.Context, query string, args ...interface{}) ([]model.Lyrics, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lyrics []model.Lyrics
+	for rows.Next() {
+		var lyric model.Lyrics
+		if err := rows.Scan(&lyric.SongID, &lyric.VerseNumber, &lyric.Text); err != nil {
+			return nil, err
+		}
+		lyrics = append(lyrics, lyric)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lyrics, nil
+}
+
+// buildSongFilter returns the WHERE conditions for filter, each prefixed
+// with AND, and their arguments.
+func (s *SQLiteStorage) buildSongFilter(filter model.SongFilter) (string, []interface{}) {
+	var conditions string
+	var args []interface{}
+
+	if filter.Group != "" {
+		if filter.GroupExact {
+			conditions += " AND group_name = ?"
+			args = append(args, filter.Group)
+		} else {
+			conditions += ` AND group_name LIKE ? ESCAPE '\'`
+			args = append(args, "%"+filter.Group+"%")
+		}
+	}
+
+	if filter.Name != "" {
+		conditions += ` AND name LIKE ? ESCAPE '\'`
+		args = append(args, "%"+filter.Name+"%")
+	}
+
+	if len(filter.IDs) > 0 {
+		placeholders := make([]string, 0, len(filter.IDs))
+		for _, id := range filter.IDs {
+			placeholders = append(placeholders, "?")
+			args = append(args, id)
+		}
+		conditions += " AND id IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	if filter.ReleaseDate != nil || filter.ReleaseDateFrom != nil || filter.ReleaseDateTo != nil {
+		conditions += " AND " + storage.KnownReleaseDate
+	}
+
+	for _, cond := range []struct {
+		expr   string
+		value  *time.Time
+		layout string
+	}{
+		{"release_date = ?", filter.ReleaseDate, dateLayout},
+		{"release_date >= ?", filter.ReleaseDateFrom, dateLayout},
+		{"release_date <= ?", filter.ReleaseDateTo, dateLayout},
+		{"inserted_at > ?", filter.InsertedAfter, timestampLayout},
+		{"inserted_at < ?", filter.InsertedBefore, timestampLayout},
+	} {
+		if cond.value != nil {
+			conditions += " AND " + cond.expr
+			args = append(args, cond.value.UTC().Format(cond.layout))
+		}
+	}
+
+	if filter.HasLink != nil {
+		if *filter.HasLink {
+			conditions += " AND COALESCE(link, '') <> ''"
+		} else {
+			conditions += " AND COALESCE(link, '') = ''"
+		}
+	}
+
+	if filter.HasLyrics != nil {
+		exists := "EXISTS (SELECT 1 FROM lyrics WHERE lyrics.song_id = songs.id)"
+		if !*filter.HasLyrics {
+			exists = "NOT " + exists
+		}
+		conditions += " AND " + exists
+	}
+
+	return conditions, args
+}
+
+// buildSongQuery mirrors the Postgres query. SQLite's LIKE is already
+// case-insensitive, but only for ASCII letters.
+func (s *SQLiteStorage) buildSongQuery(
+	filter model.SongFilter,
+	sort []model.SortKey,
+	after *model.SongCursor,
+	limit,
+	offset int,
+) (string, []interface{}, error) {
+	query := `SELECT id, group_name, name, release_date, link, inserted_at, status, client_fields
+              FROM songs WHERE deleted_at IS NULL`
+
+	conditions, args := s.buildSongFilter(filter)
+	query += conditions
+
+	orderBy, keyset, err := storage.BuildSongOrder(sort, after,
+		func(field model.SortField, value interface{}) string {
+			switch field {
+			case model.SortByReleaseDate:
+				value = value.(time.Time).Format(dateLayout)
+			case model.SortByInsertedAt:
+				value = value.(time.Time).UTC().Format(timestampLayout)
+			}
+			args = append(args, value)
+			return "?"
+		},
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if keyset != "" {
+		query += " AND " + keyset
+	}
+
+	query += " ORDER BY " + orderBy
+
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+
+	return query, args, nil
+}
+
+func (s *SQLiteStorage) UpdateSong(ctx context.Context, songID uint, updates model.SongUpdate) error {
+	if updates.ReleaseDate != "" {
+		if _, err := normalizeDate(updates.ReleaseDate); err != nil {
+			return err
+		}
+	}
+
+	songQuery, songArgs, err := s.buildUpdateSongQuery(songID, updates)
+	if err != nil && len(updates.Verses) == 0 {
+		return err
+	}
+
+	verseNumbers := make([]uint, 0, len(updates.Verses))
+	for verseNumber := range updates.Verses {
+		verseNumbers = append(verseNumbers, verseNumber)
+	}
+	sort.Slice(verseNumbers, func(i, j int) bool { return verseNumbers[i] < verseNumbers[j] })
+
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+
+		renamed := song
+		renamed.Group = cmp.Or(updates.Group, song.Group)
+		renamed.Name = cmp.Or(updates.Name, song.Name)
+		if renamed.Group != song.Group || renamed.Name != song.Name {
+			if err := existingSong(ctx, tx, renamed); !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		if songQuery != "" {
+			if _, err := tx.ExecContext(ctx, songQuery, songArgs...); err != nil {
+				return fmt.Errorf("failed to update song: %w", err)
+			}
+		}
+
+		for _, verseNumber := range verseNumbers {
+			result, err := tx.ExecContext(ctx,
+				"UPDATE lyrics SET text = ? WHERE song_id = ? AND verse_number = ?",
+				updates.Verses[verseNumber], songID, verseNumber,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update verse: %w", err)
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return fmt.Errorf("%w: verse %d", storage.ErrVerseNotFound, verseNumber)
+			}
+		}
+		return recordClientChanges(ctx, tx, song, lyrics)
+	}); err != nil {
+		return err
+	}
+
+	s.log.Info("Song updated successfully", slog.Int("song_id", int(songID)))
+	return nil
+}
+
+func (s *SQLiteStorage) buildUpdateSongQuery(songID uint, updates model.SongUpdate) (string, []interface{}, error) {
+	var sets []string
+	var args []interface{}
+
+	if updates.Group != "" {
+		sets = append(sets, "group_name = ?")
+		args = append(args, updates.Group)
+	}
+	if updates.Name != "" {
+		sets = append(sets, "name = ?")
+		args = append(args, updates.Name)
+	}
+	if updates.ReleaseDate != "" {
+		date, err := normalizeDate(updates.ReleaseDate)
+		if err != nil {
+			return "", nil, err
+		}
+		sets = append(sets, "release_date = ?")
+		args = append(args, date)
+	}
+	if updates.Link != "" {
+		sets = append(sets, "link = ?")
+		args = append(args, updates.Link)
+	}
+	if len(sets) == 0 {
+		return "", nil, fmt.Errorf("no valid fields to update")
+	}
+
+	query := "UPDATE songs SET " + strings.Join(sets, ", ") + " WHERE id = ?"
+	args = append(args, songID)
+	return query, args, nil
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// normalizeDate validates a YYYY-MM-DD date, since SQLite stores dates as
+// plain text and would otherwise accept anything.
+func normalizeDate(value string) (string, error) {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q: %w", value, err)
+	}
+	return date.Format(dateLayout), nil
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"songs_lib/internal/storage"
+	"songs_lib/internal/storage/storagetest"
+	"testing"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := NewSQLiteStorage(log, "sqlite://"+filepath.Join(t.TempDir(), "songs.db"), true)
+		if err != nil {
+			t.Fatalf("NewSQLiteStorage: %v", err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+
+		if _, err := s.db.Exec(`DELETE FROM songs; DELETE FROM sqlite_sequence`); err != nil {
+			t.Fatalf("failed to clear tables: %v", err)
+		}
+		return s
+	})
+}
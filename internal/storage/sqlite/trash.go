@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"time"
+)
+
+// songNotDeleted restricts a query over lyrics or enrichment jobs to songs
+// that are not in the trash.
+const songNotDeleted = `song_id IN (SELECT id FROM songs WHERE deleted_at IS NULL)`
+
+func (s *SQLiteStorage) GetDeletedSongs(ctx context.Context, limit, offset int) ([]model.Song, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, group_name, name, link, release_date, inserted_at, status, client_fields, deleted_at
+         FROM songs
+         WHERE deleted_at IS NOT NULL
+         ORDER BY deleted_at DESC, id
+         LIMIT ? OFFSET ?`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []model.Song
+	for rows.Next() {
+		var song model.Song
+		var link sql.NullString
+		var deletedAt time.Time
+		if err := rows.Scan(
+			&song.ID, &song.Group, &song.Name, &link, &song.ReleaseDate,
+			&song.InsertedAt, &song.Status, &song.ClientFields, &deletedAt,
+		); err != nil {
+			return nil, err
+		}
+		song.Link = link.String
+		song.DeletedAt = &deletedAt
+		songs = append(songs, song)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return songs, nil
+}
+
+func (s *SQLiteStorage) CountDeletedSongs(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM songs WHERE deleted_at IS NOT NULL`,
+	).Scan(&count)
+	return count, err
+}
+
+func (s *SQLiteStorage) RestoreSong(ctx context.Context, songID uint) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE songs SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`,
+		songID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrSongNotFound
+	}
+
+	s.log.Info("Song restored", slog.Int("song_id", int(songID)))
+	return nil
+}
+
+// PurgeDeletedSongs relies on ON DELETE CASCADE to remove the verses,
+// enrichment jobs and revisions of the songs.
+func (s *SQLiteStorage) PurgeDeletedSongs(ctx context.Context, deletedBefore time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM songs WHERE deleted_at < ?`,
+		formatTimestamp(deletedBefore),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(purged), nil
+}
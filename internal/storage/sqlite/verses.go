@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"songs_lib/internal/storage"
+)
+
+// Verse numbers are part of the lyrics primary key, which is checked row by
+// row, so renumbering first moves the affected verses past the current
+// maximum and then brings them back to their final positions.
+
+func (s *SQLiteStorage) AddVerse(ctx context.Context, songID uint, position uint, text string) (uint, error) {
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+		count := uint(len(lyrics))
+
+		if position == 0 {
+			position = count + 1
+		}
+		if position > count+1 {
+			return fmt.Errorf("%w: %d, song has %d verses", storage.ErrInvalidVersePosition, position, count)
+		}
+
+		if position <= count {
+			if err := shiftVerses(ctx, tx, songID, position, 1, count+1); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO lyrics (song_id, verse_number, text)
+             VALUES (?, ?, ?)`,
+			songID, position, text,
+		); err != nil {
+			return err
+		}
+		return recordClientChanges(ctx, tx, song, lyrics)
+	}); err != nil {
+		return 0, err
+	}
+
+	s.log.Info("Verse added successfully", slog.Int("song_id", int(songID)), slog.Int("verse_number", int(position)))
+	return position, nil
+}
+
+func (s *SQLiteStorage) DeleteVerse(ctx context.Context, songID uint, verseNumber uint) error {
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+		count := uint(len(lyrics))
+
+		result, err := tx.ExecContext(ctx,
+			`DELETE FROM lyrics WHERE song_id = ? AND verse_number = ?`,
+			songID, verseNumber,
+		)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: verse %d", storage.ErrVerseNotFound, verseNumber)
+		}
+
+		if err := shiftVerses(ctx, tx, songID, verseNumber+1, -1, count+1); err != nil {
+			return err
+		}
+		return recordClientChanges(ctx, tx, song, lyrics)
+	}); err != nil {
+		return err
+	}
+
+	s.log.Info("Verse deleted successfully", slog.Int("song_id", int(songID)), slog.Int("verse_number", int(verseNumber)))
+	return nil
+}
+
+func (s *SQLiteStorage) ReorderVerses(ctx context.Context, songID uint, order []uint) error {
+	if err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		song, lyrics, err := lockSong(ctx, tx, songID)
+		if err != nil {
+			return err
+		}
+		count := uint(len(lyrics))
+
+		if err := storage.ValidateVerseOrder(order, count); err != nil {
+			return err
+		}
+
+		offset := count + 1
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE lyrics SET verse_number = verse_number + ? WHERE song_id = ?`,
+			offset, songID,
+		); err != nil {
+			return fmt.Errorf("failed to reorder verses: %w", err)
+		}
+
+		for i, verseNumber := range order {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE lyrics SET verse_number = ? WHERE song_id = ? AND verse_number = ?`,
+				i+1, songID, verseNumber+offset,
+			); err != nil {
+				return fmt.Errorf("failed to reorder verses: %w", err)
+			}
+		}
+		return recordClientChanges(ctx, tx, song, lyrics)
+	}); err != nil {
+		return err
+	}
+
+	s.log.Info("Verses reordered successfully", slog.Int("song_id", int(songID)))
+	return nil
+}
+
+// shiftVerses moves every verse starting at from by delta positions. offset
+// must be greater than any verse number of the song.
+func shiftVerses(ctx context.Context, tx *sql.Tx, songID, from uint, delta int, offset uint) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE lyrics SET verse_number = verse_number + ?
+         WHERE song_id = ? AND verse_number >= ?`,
+		offset, songID, from,
+	); err != nil {
+		return fmt.Errorf("failed to renumber verses: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE lyrics SET verse_number = verse_number - ?
+         WHERE song_id = ? AND verse_number >= ?`,
+		int(offset)-delta, songID, offset,
+	); err != nil {
+		return fmt.Errorf("failed to renumber verses: %w", err)
+	}
+	return nil
+}
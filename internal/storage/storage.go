@@ -1,20 +1,135 @@
 package storage
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"songs_lib/internal/model"
+	"time"
 )
 
 var (
-	ErrSongNotFound = errors.New("song not found")
+	ErrSongNotFound  = errors.New("song not found")
+	ErrVerseNotFound = errors.New("verse not found")
+
+	ErrInvalidVersePosition = errors.New("invalid verse position")
+	ErrInvalidVerseOrder    = errors.New("invalid verse order")
+
+	ErrEnrichmentJobNotFound = errors.New("enrichment job not found")
+	// ErrSongPendingEnrichment is returned by RefreshSong for a song whose
+	// enrichment job has not finished yet.
+	ErrSongPendingEnrichment = errors.New("song is pending enrichment")
+
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+	ErrRevisionNotFound = errors.New("revision not found")
 )
 
+// ErrSongExists is returned by AddSong when a song with the same group and
+// name is already stored. ID is the ID of that song; Deleted is set when it is
+// in the trash.
+type ErrSongExists struct {
+	ID      uint
+	Deleted bool
+}
+
+func (e *ErrSongExists) Error() string {
+	return fmt.Sprintf("song already exists with id %d", e.ID)
+}
+
 type Storage interface {
-	AddSong(song model.Song, verses []string) (uint, error)
-	DeleteSong(songID uint) error
-	GetLyrics(songID uint, limit, offset int) ([]model.Lyrics, error)
-	GetSong(songID uint) (*model.Song, error)
-	GetAllSongs(filters map[string]string, limit, offset int) ([]model.Song, error)
-	GetAllSongLyrics(songID uint) ([]model.Lyrics, error)
-	UpdateSong(songID uint, updates model.SongUpdate) error
+	// AddSong stores a song with its verses. A song with status
+	// pending_enrichment gets an enrichment job in the same transaction. A
+	// duplicate group and name fails with *ErrSongExists.
+	AddSong(ctx context.Context, song model.Song, verses []string) (uint, error)
+	// DeleteSong moves a song to the trash. Songs in the trash are treated as
+	// missing by every other method except the trash methods below, but
+	// still keep their group and name from being added again.
+	DeleteSong(ctx context.Context, songID uint) error
+	GetLyrics(ctx context.Context, songID uint, limit, offset int) ([]model.Lyrics, error)
+	GetSong(ctx context.Context, songID uint) (*model.Song, error)
+	GetAllSongs(ctx context.Context, filter model.SongFilter, sort []model.SortKey, after *model.SongCursor, limit, offset int) ([]model.Song, error)
+	CountSongs(ctx context.Context, filter model.SongFilter) (int, error)
+	GetAllSongLyrics(ctx context.Context, songID uint) ([]model.Lyrics, error)
+	CountLyrics(ctx context.Context, songID uint) (int, error)
+	GetLyricsForSongs(ctx context.Context, songIDs []uint) (map[uint][]model.Lyrics, error)
+	// UpdateSong, the verse methods, CompleteEnrichmentJob, RefreshSong and
+	// RevertSong record what they change as a revision of the song, made by
+	// the actor of the context. Renaming a song into the group and name of
+	// another fails with *ErrSongExists.
+	UpdateSong(ctx context.Context, songID uint, updates model.SongUpdate) error
+	AddVerse(ctx context.Context, songID uint, position uint, text string) (uint, error)
+	DeleteVerse(ctx context.Context, songID uint, verseNumber uint) error
+	ReorderVerses(ctx context.Context, songID uint, order []uint) error
+	SearchLyrics(ctx context.Context, query string, limit, offset int) ([]model.SearchResult, error)
+
+	// ClaimEnrichmentJob marks the earliest due job as running until
+	// now+lease and counts the attempt. It returns ErrEnrichmentJobNotFound
+	// if no job is due.
+	ClaimEnrichmentJob(ctx context.Context, now time.Time, lease time.Duration) (*model.EnrichmentJob, error)
+	// CompleteEnrichmentJob fills in the fields the song is missing and
+	// marks the song ready and the job done.
+	CompleteEnrichmentJob(ctx context.Context, jobID uint, details model.SongEnrichment) error
+	// RetryEnrichmentJob puts a job back into the queue until runAt.
+	RetryEnrichmentJob(ctx context.Context, jobID uint, lastError string, runAt time.Time) error
+	// FailEnrichmentJob gives up on a job and marks the song enrichment_failed.
+	FailEnrichmentJob(ctx context.Context, jobID uint, lastError string) error
+	GetEnrichmentJob(ctx context.Context, songID uint) (*model.EnrichmentJob, error)
+	// RefreshSong compares the song with fetched details using DiffSong and
+	// applies the changes in one transaction. The song is marked ready. A song
+	// pending enrichment is left to its job and ErrSongPendingEnrichment is
+	// returned.
+	RefreshSong(ctx context.Context, songID uint, details model.SongEnrichment) (*model.SongDiff, error)
+	// SetSongDetails applies details the client sent like RefreshSong, but
+	// keeps the status of the song.
+	SetSongDetails(ctx context.Context, songID uint, details model.SongEnrichment) (*model.SongDiff, error)
+
+	// GetSongRevisions returns the revisions of a song, newest first.
+	GetSongRevisions(ctx context.Context, songID uint, limit, offset int) ([]model.SongRevision, error)
+	CountSongRevisions(ctx context.Context, songID uint) (int, error)
+	// RevertSong restores a song to how it was before the revision, undoing
+	// it and every later revision, and returns what changed.
+	RevertSong(ctx context.Context, songID uint, revision uint) (*model.SongDiff, error)
+
+	// GetDeletedSongs returns the songs in the trash, most recently deleted
+	// first.
+	GetDeletedSongs(ctx context.Context, limit, offset int) ([]model.Song, error)
+	CountDeletedSongs(ctx context.Context) (int, error)
+	// RestoreSong takes a song out of the trash. It returns ErrSongNotFound
+	// if the song is not in the trash.
+	RestoreSong(ctx context.Context, songID uint) error
+	// PurgeDeletedSongs permanently deletes the songs moved to the trash
+	// before deletedBefore, with everything stored for them, and returns how
+	// many were deleted.
+	PurgeDeletedSongs(ctx context.Context, deletedBefore time.Time) (int, error)
+
+	// ReserveIdempotencyKey stores an in-progress record for the key and
+	// returns nil. If the key is already taken, the existing record is
+	// returned instead. Records created before expiredBefore, and in-progress
+	// ones created before staleBefore, are deleted first, so their keys can be
+	// used again.
+	ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, now, expiredBefore, staleBefore time.Time) (*model.IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the response of a reserved key.
+	CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, location string, body []byte) error
+	// ReleaseIdempotencyKey deletes a key, so the request can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+
+	Close() error
+}
+
+// ValidateVerseOrder checks that order lists every verse number from 1 to
+// count exactly once, as required by ReorderVerses.
+func ValidateVerseOrder(order []uint, count uint) error {
+	if uint(len(order)) != count {
+		return fmt.Errorf("%w: got %d verses, song has %d", ErrInvalidVerseOrder, len(order), count)
+	}
+
+	seen := make(map[uint]bool, len(order))
+	for _, verseNumber := range order {
+		if verseNumber == 0 || verseNumber > count || seen[verseNumber] {
+			return fmt.Errorf("%w: unexpected verse %d", ErrInvalidVerseOrder, verseNumber)
+		}
+		seen[verseNumber] = true
+	}
+	return nil
 }
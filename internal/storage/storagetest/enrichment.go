@@ -0,0 +1,234 @@
+package storagetest
+
+import (
+	"errors"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"testing"
+	"time"
+)
+
+func addPendingSong(t *testing.T, s storage.Storage, group, name string) uint {
+	t.Helper()
+	id, err := s.AddSong(ctx, model.Song{
+		Group:  group,
+		Name:   name,
+		Status: model.SongStatusPendingEnrichment,
+	}, nil)
+	if err != nil {
+		t.Fatalf("AddSong(%q, %q): %v", group, name, err)
+	}
+	return id
+}
+
+func claimJob(t *testing.T, s storage.Storage, now time.Time) *model.EnrichmentJob {
+	t.Helper()
+	job, err := s.ClaimEnrichmentJob(ctx, now, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimEnrichmentJob: %v", err)
+	}
+	return job
+}
+
+func assertNoDueJob(t *testing.T, s storage.Storage, now time.Time) {
+	t.Helper()
+	if job, err := s.ClaimEnrichmentJob(ctx, now, time.Minute); !errors.Is(err, storage.ErrEnrichmentJobNotFound) {
+		t.Fatalf("ClaimEnrichmentJob = %+v, %v, want ErrEnrichmentJobNotFound", job, err)
+	}
+}
+
+func testSongStatusDefault(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != model.SongStatusReady {
+		t.Errorf("Status = %q, want %q", song.Status, model.SongStatusReady)
+	}
+
+	if _, err := s.GetEnrichmentJob(ctx, id); !errors.Is(err, storage.ErrEnrichmentJobNotFound) {
+		t.Errorf("GetEnrichmentJob error = %v, want ErrEnrichmentJobNotFound", err)
+	}
+	assertNoDueJob(t, s, time.Now().Add(time.Hour))
+}
+
+func testEnrichmentJobComplete(t *testing.T, s storage.Storage) {
+	first := addPendingSong(t, s, "Muse", "Uprising")
+	second := addPendingSong(t, s, "Muse", "Resistance")
+
+	songs, err := s.GetAllSongs(ctx, model.SongFilter{}, nil, nil, 0, 0)
+	if err != nil {
+		t.Fatalf("GetAllSongs: %v", err)
+	}
+	for _, song := range songs {
+		if song.Status != model.SongStatusPendingEnrichment {
+			t.Errorf("song %d status = %q, want %q", song.ID, song.Status, model.SongStatusPendingEnrichment)
+		}
+	}
+
+	queued, err := s.GetEnrichmentJob(ctx, first)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJob: %v", err)
+	}
+	if queued.Status != model.EnrichmentJobPending || queued.Attempts != 0 || queued.SongID != first {
+		t.Errorf("queued job = %+v", queued)
+	}
+
+	now := time.Now().Add(time.Second)
+	job := claimJob(t, s, now)
+	if job.SongID != first || job.Status != model.EnrichmentJobRunning || job.Attempts != 1 {
+		t.Errorf("claimed job = %+v, want running first attempt for song %d", job, first)
+	}
+	if other := claimJob(t, s, now); other.SongID != second {
+		t.Errorf("second claim got song %d, want %d", other.SongID, second)
+	}
+	assertNoDueJob(t, s, now)
+
+	if err := s.CompleteEnrichmentJob(ctx, job.ID, model.SongEnrichment{
+		Link:        "https://example.com/uprising",
+		ReleaseDate: date(t, "2009-09-07"),
+		Verses:      []string{"one", "two"},
+	}); err != nil {
+		t.Fatalf("CompleteEnrichmentJob: %v", err)
+	}
+
+	song, err := s.GetSong(ctx, first)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != model.SongStatusReady || song.Link != "https://example.com/uprising" ||
+		!song.ReleaseDate.Equal(date(t, "2009-09-07")) {
+		t.Errorf("enriched song = %+v", song)
+	}
+	assertVerses(t, s, first, "one", "two")
+
+	done, err := s.GetEnrichmentJob(ctx, first)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJob: %v", err)
+	}
+	if done.Status != model.EnrichmentJobDone || done.Attempts != 1 {
+		t.Errorf("completed job = %+v", done)
+	}
+}
+
+func testEnrichmentJobKeepsSongData(t *testing.T, s storage.Storage) {
+	id, err := s.AddSong(ctx, model.Song{
+		Group:  "Muse",
+		Name:   "Uprising",
+		Link:   "https://example.com/manual",
+		Status: model.SongStatusPendingEnrichment,
+	}, []string{"manual verse"})
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+
+	job := claimJob(t, s, time.Now().Add(time.Second))
+	if err := s.CompleteEnrichmentJob(ctx, job.ID, model.SongEnrichment{
+		Link:        "https://example.com/fetched",
+		ReleaseDate: date(t, "2009-09-07"),
+		Verses:      []string{"fetched verse"},
+	}); err != nil {
+		t.Fatalf("CompleteEnrichmentJob: %v", err)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Link != "https://example.com/manual" {
+		t.Errorf("Link = %q, want the manual link", song.Link)
+	}
+	if !song.ReleaseDate.Equal(date(t, "2009-09-07")) {
+		t.Errorf("ReleaseDate = %v, want the fetched date", song.ReleaseDate)
+	}
+	assertVerses(t, s, id, "manual verse")
+}
+
+func testEnrichmentJobRetry(t *testing.T, s storage.Storage) {
+	id := addPendingSong(t, s, "Muse", "Uprising")
+
+	now := time.Now().Add(time.Second)
+	job := claimJob(t, s, now)
+	if err := s.RetryEnrichmentJob(ctx, job.ID, "upstream is down", now.Add(time.Hour)); err != nil {
+		t.Fatalf("RetryEnrichmentJob: %v", err)
+	}
+
+	queued, err := s.GetEnrichmentJob(ctx, id)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJob: %v", err)
+	}
+	if queued.Status != model.EnrichmentJobPending || queued.LastError != "upstream is down" {
+		t.Errorf("retried job = %+v", queued)
+	}
+
+	assertNoDueJob(t, s, now.Add(time.Minute))
+
+	retried := claimJob(t, s, now.Add(2*time.Hour))
+	if retried.ID != job.ID || retried.Attempts != 2 {
+		t.Errorf("retried job = %+v, want second attempt of job %d", retried, job.ID)
+	}
+}
+
+func testEnrichmentJobLeaseExpired(t *testing.T, s storage.Storage) {
+	addPendingSong(t, s, "Muse", "Uprising")
+
+	now := time.Now().Add(time.Second)
+	job := claimJob(t, s, now)
+	assertNoDueJob(t, s, now.Add(30*time.Second))
+
+	// the worker never reported back
+	reclaimed := claimJob(t, s, now.Add(2*time.Minute))
+	if reclaimed.ID != job.ID || reclaimed.Attempts != 2 {
+		t.Errorf("reclaimed job = %+v, want second attempt of job %d", reclaimed, job.ID)
+	}
+}
+
+func testEnrichmentJobFail(t *testing.T, s storage.Storage) {
+	id := addPendingSong(t, s, "Muse", "Uprising")
+
+	job := claimJob(t, s, time.Now().Add(time.Second))
+	if err := s.FailEnrichmentJob(ctx, job.ID, "song not found"); err != nil {
+		t.Fatalf("FailEnrichmentJob: %v", err)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != model.SongStatusEnrichmentFailed {
+		t.Errorf("Status = %q, want %q", song.Status, model.SongStatusEnrichmentFailed)
+	}
+
+	failed, err := s.GetEnrichmentJob(ctx, id)
+	if err != nil {
+		t.Fatalf("GetEnrichmentJob: %v", err)
+	}
+	if failed.Status != model.EnrichmentJobFailed || failed.LastError != "song not found" {
+		t.Errorf("failed job = %+v", failed)
+	}
+	assertNoDueJob(t, s, time.Now().Add(time.Hour))
+}
+
+func testEnrichmentJobNotFound(t *testing.T, s storage.Storage) {
+	id := addPendingSong(t, s, "Muse", "Uprising")
+	if err := s.DeleteSong(ctx, id); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	if _, err := s.GetEnrichmentJob(ctx, id); !errors.Is(err, storage.ErrEnrichmentJobNotFound) {
+		t.Errorf("GetEnrichmentJob(deleted song) error = %v, want ErrEnrichmentJobNotFound", err)
+	}
+	assertNoDueJob(t, s, time.Now().Add(time.Hour))
+
+	if err := s.CompleteEnrichmentJob(ctx, 9999, model.SongEnrichment{}); !errors.Is(err, storage.ErrEnrichmentJobNotFound) {
+		t.Errorf("CompleteEnrichmentJob error = %v, want ErrEnrichmentJobNotFound", err)
+	}
+	if err := s.RetryEnrichmentJob(ctx, 9999, "", time.Now()); !errors.Is(err, storage.ErrEnrichmentJobNotFound) {
+		t.Errorf("RetryEnrichmentJob error = %v, want ErrEnrichmentJobNotFound", err)
+	}
+	if err := s.FailEnrichmentJob(ctx, 9999, ""); !errors.Is(err, storage.ErrEnrichmentJobNotFound) {
+		t.Errorf("FailEnrichmentJob error = %v, want ErrEnrichmentJobNotFound", err)
+	}
+}
@@ -0,0 +1,98 @@
+package storagetest
+
+import (
+	"errors"
+	"songs_lib/internal/storage"
+	"testing"
+	"time"
+)
+
+func testIdempotencyKey(t *testing.T, s storage.Storage) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	existing, err := s.ReserveIdempotencyKey(ctx, "key-1", "fingerprint", now, now.Add(-time.Hour), now.Add(-time.Minute))
+	if err != nil || existing != nil {
+		t.Fatalf("ReserveIdempotencyKey = %+v, %v, want a new reservation", existing, err)
+	}
+
+	existing, err = s.ReserveIdempotencyKey(ctx, "key-1", "other", now, now.Add(-time.Hour), now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("ReserveIdempotencyKey again: %v", err)
+	}
+	if existing == nil || existing.Fingerprint != "fingerprint" || existing.StatusCode != 0 {
+		t.Fatalf("existing record = %+v, want the in-progress reservation", existing)
+	}
+
+	body := []byte(`{"id":1}`)
+	if err := s.CompleteIdempotencyKey(ctx, "key-1", 201, "/api/v1/song/1", body); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+	existing, err = s.ReserveIdempotencyKey(ctx, "key-1", "fingerprint", now, now.Add(-time.Hour), now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("ReserveIdempotencyKey after completion: %v", err)
+	}
+	if existing == nil || existing.StatusCode != 201 || existing.Location != "/api/v1/song/1" || string(existing.Body) != string(body) {
+		t.Fatalf("completed record = %+v", existing)
+	}
+
+	// the record expires
+	existing, err = s.ReserveIdempotencyKey(ctx, "key-1", "new", now.Add(2*time.Hour), now.Add(time.Hour), now.Add(time.Hour))
+	if err != nil || existing != nil {
+		t.Fatalf("ReserveIdempotencyKey after expiry = %+v, %v, want a new reservation", existing, err)
+	}
+
+	if err := s.ReleaseIdempotencyKey(ctx, "key-1"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey: %v", err)
+	}
+	existing, err = s.ReserveIdempotencyKey(ctx, "key-1", "again", now, now.Add(-time.Hour), now.Add(-time.Minute))
+	if err != nil || existing != nil {
+		t.Fatalf("ReserveIdempotencyKey after release = %+v, %v, want a new reservation", existing, err)
+	}
+}
+
+func testIdempotencyKeyLease(t *testing.T, s storage.Storage) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if _, err := s.ReserveIdempotencyKey(ctx, "stuck", "fingerprint", now, now.Add(-time.Hour), now.Add(-time.Minute)); err != nil {
+		t.Fatalf("ReserveIdempotencyKey: %v", err)
+	}
+	if _, err := s.ReserveIdempotencyKey(ctx, "done", "fingerprint", now, now.Add(-time.Hour), now.Add(-time.Minute)); err != nil {
+		t.Fatalf("ReserveIdempotencyKey: %v", err)
+	}
+	if err := s.CompleteIdempotencyKey(ctx, "done", 201, "", nil); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+
+	// within the lease the reservation is kept
+	later := now.Add(30 * time.Second)
+	existing, err := s.ReserveIdempotencyKey(ctx, "stuck", "fingerprint", later, later.Add(-time.Hour), later.Add(-time.Minute))
+	if err != nil || existing == nil || existing.StatusCode != 0 {
+		t.Fatalf("ReserveIdempotencyKey within the lease = %+v, %v, want the in-progress reservation", existing, err)
+	}
+
+	// a retry takes over a reservation whose lease has ended
+	later = now.Add(2 * time.Minute)
+	existing, err = s.ReserveIdempotencyKey(ctx, "stuck", "fingerprint", later, later.Add(-time.Hour), later.Add(-time.Minute))
+	if err != nil || existing != nil {
+		t.Fatalf("ReserveIdempotencyKey after the lease = %+v, %v, want a new reservation", existing, err)
+	}
+	existing, err = s.ReserveIdempotencyKey(ctx, "stuck", "fingerprint", later, later.Add(-time.Hour), later.Add(-time.Minute))
+	if err != nil || existing == nil || existing.StatusCode != 0 {
+		t.Fatalf("ReserveIdempotencyKey after the takeover = %+v, %v, want the new reservation", existing, err)
+	}
+
+	// completed records are kept until they expire
+	existing, err = s.ReserveIdempotencyKey(ctx, "done", "fingerprint", later, later.Add(-time.Hour), later.Add(-time.Minute))
+	if err != nil || existing == nil || existing.StatusCode != 201 {
+		t.Fatalf("ReserveIdempotencyKey of a completed key = %+v, %v, want the completed record", existing, err)
+	}
+}
+
+func testIdempotencyKeyNotFound(t *testing.T, s storage.Storage) {
+	if err := s.CompleteIdempotencyKey(ctx, "missing", 201, "", nil); !errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+		t.Errorf("CompleteIdempotencyKey error = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+	if err := s.ReleaseIdempotencyKey(ctx, "missing"); !errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+		t.Errorf("ReleaseIdempotencyKey error = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+}
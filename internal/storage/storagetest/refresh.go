@@ -0,0 +1,270 @@
+package storagetest
+
+import (
+	"errors"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"testing"
+	"time"
+)
+
+func refreshSong(t *testing.T, s storage.Storage, id uint, details model.SongEnrichment) *model.SongDiff {
+	t.Helper()
+	diff, err := s.RefreshSong(ctx, id, details)
+	if err != nil {
+		t.Fatalf("RefreshSong(%d): %v", id, err)
+	}
+	return diff
+}
+
+func testRefreshSong(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2", "v3")
+
+	diff := refreshSong(t, s, id, model.SongEnrichment{
+		Link:        "https://example.com/new",
+		ReleaseDate: date(t, "2009-09-14"),
+		Verses:      []string{"v1", "v2 fixed"},
+	})
+
+	wantFields := []model.FieldChange{
+		{Field: "link", Old: "https://example.com/Uprising", New: "https://example.com/new"},
+		{Field: "release_date", Old: "2009-09-07", New: "2009-09-14"},
+	}
+	if !equal(diff.Fields, wantFields) {
+		t.Errorf("Fields = %+v, want %+v", diff.Fields, wantFields)
+	}
+	wantVerses := []model.VerseChange{
+		{VerseNumber: 2, Kind: model.ChangeModified, Old: "v2", New: "v2 fixed"},
+		{VerseNumber: 3, Kind: model.ChangeRemoved, Old: "v3"},
+	}
+	if !equal(diff.Verses, wantVerses) {
+		t.Errorf("Verses = %+v, want %+v", diff.Verses, wantVerses)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Link != "https://example.com/new" || !song.ReleaseDate.Equal(date(t, "2009-09-14")) {
+		t.Errorf("song = %+v, want refreshed link and release date", song)
+	}
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if got, want := verseTexts(lyrics), []string{"v1", "v2 fixed"}; !equal(got, want) {
+		t.Errorf("verses = %v, want %v", got, want)
+	}
+
+	diff = refreshSong(t, s, id, model.SongEnrichment{Verses: []string{"v1", "v2 fixed", "v3 new"}})
+	wantVerses = []model.VerseChange{{VerseNumber: 3, Kind: model.ChangeAdded, New: "v3 new"}}
+	if len(diff.Fields) != 0 || !equal(diff.Verses, wantVerses) {
+		t.Errorf("diff = %+v, want only added verse 3", diff)
+	}
+}
+
+func testRefreshSongUnchanged(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2")
+
+	diff := refreshSong(t, s, id, model.SongEnrichment{
+		Link:        "https://example.com/Uprising",
+		ReleaseDate: date(t, "2009-09-07"),
+		Verses:      []string{"v1", "v2"},
+	})
+	if !diff.Empty() {
+		t.Errorf("diff = %+v, want empty", diff)
+	}
+	if diff.Song.ID != id || diff.Song.Status != model.SongStatusReady {
+		t.Errorf("diff song = %+v", diff.Song)
+	}
+
+	// an incomplete response must not erase stored data
+	diff = refreshSong(t, s, id, model.SongEnrichment{})
+	if !diff.Empty() {
+		t.Errorf("diff for empty details = %+v, want empty", diff)
+	}
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if got, want := verseTexts(lyrics), []string{"v1", "v2"}; !equal(got, want) {
+		t.Errorf("verses = %v, want %v", got, want)
+	}
+}
+
+func testRefreshSongPending(t *testing.T, s storage.Storage) {
+	id := addPendingSong(t, s, "Muse", "Uprising")
+	details := model.SongEnrichment{
+		Link:        "https://example.com/Uprising",
+		ReleaseDate: date(t, "2009-09-07"),
+		Verses:      []string{"v1"},
+	}
+
+	// the song is left to its enrichment job
+	if _, err := s.RefreshSong(ctx, id, details); !errors.Is(err, storage.ErrSongPendingEnrichment) {
+		t.Fatalf("RefreshSong(pending) error = %v, want ErrSongPendingEnrichment", err)
+	}
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != model.SongStatusPendingEnrichment || song.Link != "" {
+		t.Errorf("song = %+v, want it unchanged", song)
+	}
+
+	// once the job gave up, a refresh fills the song in and marks it ready
+	job := claimJob(t, s, time.Now().Add(time.Second))
+	if err := s.FailEnrichmentJob(ctx, job.ID, "upstream down"); err != nil {
+		t.Fatalf("FailEnrichmentJob: %v", err)
+	}
+	diff := refreshSong(t, s, id, details)
+	wantFields := []model.FieldChange{
+		{Field: "link", New: "https://example.com/Uprising"},
+		{Field: "release_date", New: "2009-09-07"},
+	}
+	if !equal(diff.Fields, wantFields) {
+		t.Errorf("Fields = %+v, want %+v", diff.Fields, wantFields)
+	}
+
+	song, err = s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != model.SongStatusReady {
+		t.Errorf("Status = %q, want %q", song.Status, model.SongStatusReady)
+	}
+}
+
+func testRefreshSongNotFound(t *testing.T, s storage.Storage) {
+	if _, err := s.RefreshSong(ctx, 999, model.SongEnrichment{Link: "x"}); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("RefreshSong error = %v, want ErrSongNotFound", err)
+	}
+}
+
+func testSetSongDetailsKeepsStatus(t *testing.T, s storage.Storage) {
+	id := addPendingSong(t, s, "Muse", "Uprising")
+
+	diff, err := s.SetSongDetails(ctx, id, model.SongEnrichment{Link: "https://example.com/mine"})
+	if err != nil {
+		t.Fatalf("SetSongDetails: %v", err)
+	}
+	if len(diff.Fields) != 1 || diff.Song.Status != model.SongStatusPendingEnrichment {
+		t.Errorf("diff = %+v, want the link changed and the status kept", diff)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != model.SongStatusPendingEnrichment || song.Link != "https://example.com/mine" {
+		t.Errorf("song = %+v, want the link set and still pending", song)
+	}
+
+	// the job still runs and keeps what the client set
+	job := claimJob(t, s, time.Now().Add(time.Second))
+	if err := s.CompleteEnrichmentJob(ctx, job.ID, model.SongEnrichment{
+		Link:        "https://example.com/Uprising",
+		ReleaseDate: date(t, "2009-09-07"),
+	}); err != nil {
+		t.Fatalf("CompleteEnrichmentJob: %v", err)
+	}
+	if song, err = s.GetSong(ctx, id); err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Status != model.SongStatusReady || song.Link != "https://example.com/mine" {
+		t.Errorf("song = %+v, want it ready with the client link", song)
+	}
+
+	if _, err := s.SetSongDetails(ctx, 999, model.SongEnrichment{Link: "x"}); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("SetSongDetails error = %v, want ErrSongNotFound", err)
+	}
+}
+
+func testRefreshSongKeepsClientFields(t *testing.T, s storage.Storage) {
+	id, err := s.AddSong(ctx, model.Song{
+		Group:        "Muse",
+		Name:         "Uprising",
+		Link:         "https://example.com/mine",
+		ClientFields: model.FieldLink,
+	}, []string{"v1"})
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+
+	diff := refreshSong(t, s, id, model.SongEnrichment{
+		Link:        "https://example.com/Uprising",
+		ReleaseDate: date(t, "2009-09-07"),
+		Verses:      []string{"v1 fixed"},
+	})
+	if len(diff.Fields) != 1 || diff.Fields[0].Field != "release_date" || len(diff.Verses) != 1 {
+		t.Errorf("diff = %+v, want the release date and the verse changed", diff)
+	}
+
+	// details the client sends later become theirs as well
+	if _, err := s.SetSongDetails(ctx, id, model.SongEnrichment{Verses: []string{"mine"}}); err != nil {
+		t.Fatalf("SetSongDetails: %v", err)
+	}
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if want := model.FieldLink | model.FieldVerses; song.ClientFields != want {
+		t.Errorf("ClientFields = %b, want %b", song.ClientFields, want)
+	}
+
+	diff = refreshSong(t, s, id, model.SongEnrichment{
+		Link:   "https://example.com/Uprising",
+		Verses: []string{"v1 fixed"},
+	})
+	if !diff.Empty() {
+		t.Errorf("diff = %+v, want the client details kept", diff)
+	}
+}
+
+func testRefreshSongKeepsEdits(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2")
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Link: "https://example.com/mine", ReleaseDate: "2010-01-01"}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if _, err := s.AddVerse(ctx, id, 0, "v3"); err != nil {
+		t.Fatalf("AddVerse: %v", err)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.ClientFields != model.AllSongFields {
+		t.Errorf("ClientFields = %b, want every edited field", song.ClientFields)
+	}
+
+	diff := refreshSong(t, s, id, model.SongEnrichment{
+		Link:        "https://example.com/Uprising",
+		ReleaseDate: date(t, "2009-09-07"),
+		Verses:      []string{"v1", "v2"},
+	})
+	if !diff.Empty() {
+		t.Errorf("diff = %+v, want the edits kept", diff)
+	}
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if diff.Song.Link != "https://example.com/mine" || diff.Song.ReleaseDate.Format(dateLayout) != "2010-01-01" ||
+		!equal(verseTexts(lyrics), []string{"v1", "v2", "v3"}) {
+		t.Errorf("song = %+v, verses = %v, want the edits kept", diff.Song, verseTexts(lyrics))
+	}
+}
+
+func testRefreshSongKeepsRevert(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1")
+	refreshSong(t, s, id, model.SongEnrichment{Link: "https://example.com/wrong"})
+
+	if _, err := s.RevertSong(ctx, id, 1); err != nil {
+		t.Fatalf("RevertSong: %v", err)
+	}
+	diff := refreshSong(t, s, id, model.SongEnrichment{Link: "https://example.com/wrong", Verses: []string{"v1 fixed"}})
+	if diff.Song.Link != "https://example.com/Uprising" || len(diff.Fields) != 0 || len(diff.Verses) != 1 {
+		t.Errorf("diff = %+v, want the reverted link kept and the verse refreshed", diff)
+	}
+}
@@ -0,0 +1,234 @@
+package storagetest
+
+import (
+	"errors"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"testing"
+	"time"
+)
+
+func songRevisions(t *testing.T, s storage.Storage, id uint) []model.SongRevision {
+	t.Helper()
+	revisions, err := s.GetSongRevisions(ctx, id, 100, 0)
+	if err != nil {
+		t.Fatalf("GetSongRevisions(%d): %v", id, err)
+	}
+	return revisions
+}
+
+func testSongRevisions(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2")
+
+	if err := s.UpdateSong(storage.WithActor(ctx, "editor"), id, model.SongUpdate{
+		Name:   "Uprising (Live)",
+		Verses: map[uint]string{2: "v2 fixed"},
+	}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if _, err := s.AddVerse(ctx, id, 0, "v3"); err != nil {
+		t.Fatalf("AddVerse: %v", err)
+	}
+	// an update without changes records nothing
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Name: "Uprising (Live)"}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+
+	revisions := songRevisions(t, s, id)
+	if len(revisions) != 2 {
+		t.Fatalf("got %d revisions, want 2: %+v", len(revisions), revisions)
+	}
+
+	latest, first := revisions[0], revisions[1]
+	if first.Number != 1 || first.SongID != id || first.Actor != "editor" || first.CreatedAt.IsZero() {
+		t.Errorf("first revision = %+v", first)
+	}
+	wantFields := []model.FieldChange{{Field: "name", Old: "Uprising", New: "Uprising (Live)"}}
+	if !equal(first.Fields, wantFields) {
+		t.Errorf("first revision fields = %+v, want %+v", first.Fields, wantFields)
+	}
+	wantVerses := []model.VerseChange{{VerseNumber: 2, Kind: model.ChangeModified, Old: "v2", New: "v2 fixed"}}
+	if !equal(first.Verses, wantVerses) {
+		t.Errorf("first revision verses = %+v, want %+v", first.Verses, wantVerses)
+	}
+
+	if latest.Number != 2 || latest.Actor != storage.SystemActor || len(latest.Fields) != 0 {
+		t.Errorf("latest revision = %+v", latest)
+	}
+	wantVerses = []model.VerseChange{{VerseNumber: 3, Kind: model.ChangeAdded, New: "v3"}}
+	if !equal(latest.Verses, wantVerses) {
+		t.Errorf("latest revision verses = %+v, want %+v", latest.Verses, wantVerses)
+	}
+
+	count, err := s.CountSongRevisions(ctx, id)
+	if err != nil {
+		t.Fatalf("CountSongRevisions: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("CountSongRevisions = %d, want 2", count)
+	}
+
+	page, err := s.GetSongRevisions(ctx, id, 1, 1)
+	if err != nil {
+		t.Fatalf("GetSongRevisions: %v", err)
+	}
+	if len(page) != 1 || page[0].Number != 1 {
+		t.Errorf("second page = %+v, want revision 1", page)
+	}
+}
+
+func testSongRevisionsRecordEveryChange(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2", "v3")
+
+	if err := s.DeleteVerse(ctx, id, 1); err != nil {
+		t.Fatalf("DeleteVerse: %v", err)
+	}
+	if err := s.ReorderVerses(ctx, id, []uint{2, 1}); err != nil {
+		t.Fatalf("ReorderVerses: %v", err)
+	}
+	refreshSong(t, s, id, model.SongEnrichment{Link: "https://example.com/new"})
+
+	pending := addPendingSong(t, s, "Muse", "Resistance")
+	job := claimJob(t, s, time.Now().Add(time.Second))
+	if err := s.CompleteEnrichmentJob(ctx, job.ID, model.SongEnrichment{Verses: []string{"fetched"}}); err != nil {
+		t.Fatalf("CompleteEnrichmentJob: %v", err)
+	}
+
+	if got := songRevisions(t, s, id); len(got) != 3 {
+		t.Errorf("got %d revisions, want one per change: %+v", len(got), got)
+	}
+	if got := songRevisions(t, s, pending); len(got) != 1 {
+		t.Errorf("got %d revisions of the enriched song, want 1: %+v", len(got), got)
+	}
+}
+
+func testRevertSong(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2", "v3")
+
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{
+		ReleaseDate: "2009-09-14",
+		Verses:      map[uint]string{1: "v1 fixed"},
+	}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	vandal := storage.WithActor(ctx, "vandal")
+	if err := s.UpdateSong(vandal, id, model.SongUpdate{
+		Name:   "Downfall",
+		Link:   "https://spam.example.com",
+		Verses: map[uint]string{2: "spam"},
+	}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if err := s.DeleteVerse(vandal, id, 3); err != nil {
+		t.Fatalf("DeleteVerse: %v", err)
+	}
+
+	diff, err := s.RevertSong(storage.WithActor(ctx, "editor"), id, 2)
+	if err != nil {
+		t.Fatalf("RevertSong: %v", err)
+	}
+	if diff.Empty() {
+		t.Error("RevertSong reported no changes")
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Name != "Uprising" || song.Link != "https://example.com/Uprising" ||
+		song.ReleaseDate.Format(dateLayout) != "2009-09-14" {
+		t.Errorf("song = %+v, want the state after revision 1", song)
+	}
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if got, want := verseTexts(lyrics), []string{"v1 fixed", "v2", "v3"}; !equal(got, want) {
+		t.Errorf("verses = %v, want %v", got, want)
+	}
+
+	revisions := songRevisions(t, s, id)
+	if len(revisions) != 4 || revisions[0].Number != 4 || revisions[0].Actor != "editor" {
+		t.Fatalf("revisions = %+v, want the revert recorded as revision 4", revisions)
+	}
+
+	// the revert is a revision itself and can be undone
+	if _, err := s.RevertSong(ctx, id, 4); err != nil {
+		t.Fatalf("RevertSong: %v", err)
+	}
+	lyrics, err = s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if got, want := verseTexts(lyrics), []string{"v1 fixed", "spam"}; !equal(got, want) {
+		t.Errorf("verses after undoing the revert = %v, want %v", got, want)
+	}
+
+	if _, err := s.RevertSong(ctx, id, 1); err != nil {
+		t.Fatalf("RevertSong: %v", err)
+	}
+	song, err = s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	lyrics, err = s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if song.Name != "Uprising" || song.ReleaseDate.Format(dateLayout) != "2009-09-07" ||
+		!equal(verseTexts(lyrics), []string{"v1", "v2", "v3"}) {
+		t.Errorf("song = %+v, verses = %v, want the song as added", song, verseTexts(lyrics))
+	}
+}
+
+func testRevertSongNameTaken(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Name: "Resistance"}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	other := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+
+	_, err := s.RevertSong(ctx, id, 1)
+	var exists *storage.ErrSongExists
+	if !errors.As(err, &exists) || exists.ID != other || exists.Deleted {
+		t.Fatalf("RevertSong = %v, want ErrSongExists with id %d", err, other)
+	}
+	if got := songRevisions(t, s, id); len(got) != 1 {
+		t.Errorf("got %d revisions, want the failed revert not recorded", len(got))
+	}
+}
+
+func testRevertSongNameTrashed(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Name: "Resistance"}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	other := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+	deleteSong(t, s, other)
+
+	_, err := s.RevertSong(ctx, id, 1)
+	var exists *storage.ErrSongExists
+	if !errors.As(err, &exists) || exists.ID != other || !exists.Deleted {
+		t.Fatalf("RevertSong = %v, want ErrSongExists for the deleted song %d", err, other)
+	}
+}
+
+func testRevertSongNotFound(t *testing.T, s storage.Storage) {
+	if _, err := s.RevertSong(ctx, 999, 1); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("RevertSong(missing song) = %v, want ErrSongNotFound", err)
+	}
+	if _, err := s.GetSongRevisions(ctx, 999, 10, 0); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("GetSongRevisions(missing song) = %v, want ErrSongNotFound", err)
+	}
+
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1")
+	if _, err := s.RevertSong(ctx, id, 1); !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Errorf("RevertSong(no revisions) = %v, want ErrRevisionNotFound", err)
+	}
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Link: "https://example.com/new"}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if _, err := s.RevertSong(ctx, id, 2); !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Errorf("RevertSong(future revision) = %v, want ErrRevisionNotFound", err)
+	}
+}
@@ -0,0 +1,1134 @@
+// Package storagetest provides a conformance suite that every
+// storage.Storage implementation is expected to pass.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var ctx = context.Background()
+
+// Factory returns an empty storage for a single subtest. Implementations are
+// responsible for releasing any resources through t.Cleanup.
+type Factory func(t *testing.T) storage.Storage
+
+// Run executes the whole suite against storages produced by newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage)
+	}{
+		{"AddAndGetSong", testAddAndGetSong},
+		{"AddSongDuplicate", testAddSongDuplicate},
+		{"GetSongNotFound", testGetSongNotFound},
+		{"VerseOrdering", testVerseOrdering},
+		{"GetLyricsPagination", testGetLyricsPagination},
+		{"GetLyricsForSongs", testGetLyricsForSongs},
+		{"GetAllSongsFilters", testGetAllSongsFilters},
+		{"GetAllSongsPagination", testGetAllSongsPagination},
+		{"CountSongs", testCountSongs},
+		{"CountLyrics", testCountLyrics},
+		{"GetAllSongsCursor", testGetAllSongsCursor},
+		{"GetAllSongsSort", testGetAllSongsSort},
+		{"GetAllSongsSortCursor", testGetAllSongsSortCursor},
+		{"GetAllSongsInvalidSort", testGetAllSongsInvalidSort},
+		{"GetAllSongsUnknownReleaseDate", testGetAllSongsUnknownReleaseDate},
+		{"DeleteSongCascade", testDeleteSongCascade},
+		{"DeleteSongNotFound", testDeleteSongNotFound},
+		{"UpdateSongPartial", testUpdateSongPartial},
+		{"UpdateSongVerses", testUpdateSongVerses},
+		{"UpdateSongEmpty", testUpdateSongEmpty},
+		{"UpdateSongNotFound", testUpdateSongNotFound},
+		{"UpdateSongNameTaken", testUpdateSongNameTaken},
+		{"UpdateSongVerseNotFound", testUpdateSongVerseNotFound},
+		{"AddVerse", testAddVerse},
+		{"AddVerseInvalid", testAddVerseInvalid},
+		{"DeleteVerse", testDeleteVerse},
+		{"DeleteVerseNotFound", testDeleteVerseNotFound},
+		{"ReorderVerses", testReorderVerses},
+		{"ReorderVersesInvalid", testReorderVersesInvalid},
+		{"SearchLyrics", testSearchLyrics},
+		{"SearchLyricsPagination", testSearchLyricsPagination},
+		{"SongStatusDefault", testSongStatusDefault},
+		{"EnrichmentJobComplete", testEnrichmentJobComplete},
+		{"EnrichmentJobKeepsSongData", testEnrichmentJobKeepsSongData},
+		{"EnrichmentJobRetry", testEnrichmentJobRetry},
+		{"EnrichmentJobLeaseExpired", testEnrichmentJobLeaseExpired},
+		{"EnrichmentJobFail", testEnrichmentJobFail},
+		{"EnrichmentJobNotFound", testEnrichmentJobNotFound},
+		{"RefreshSong", testRefreshSong},
+		{"RefreshSongUnchanged", testRefreshSongUnchanged},
+		{"RefreshSongPending", testRefreshSongPending},
+		{"RefreshSongNotFound", testRefreshSongNotFound},
+		{"SetSongDetailsKeepsStatus", testSetSongDetailsKeepsStatus},
+		{"RefreshSongKeepsClientFields", testRefreshSongKeepsClientFields},
+		{"RefreshSongKeepsEdits", testRefreshSongKeepsEdits},
+		{"RefreshSongKeepsRevert", testRefreshSongKeepsRevert},
+		{"SongRevisions", testSongRevisions},
+		{"SongRevisionsRecordEveryChange", testSongRevisionsRecordEveryChange},
+		{"RevertSong", testRevertSong},
+		{"RevertSongNameTaken", testRevertSongNameTaken},
+		{"RevertSongNameTrashed", testRevertSongNameTrashed},
+		{"RevertSongNotFound", testRevertSongNotFound},
+		{"IdempotencyKey", testIdempotencyKey},
+		{"IdempotencyKeyLease", testIdempotencyKeyLease},
+		{"IdempotencyKeyNotFound", testIdempotencyKeyNotFound},
+		{"Trash", testTrash},
+		{"TrashHidesSong", testTrashHidesSong},
+		{"RestoreSongNotFound", testRestoreSongNotFound},
+		{"PurgeDeletedSongs", testPurgeDeletedSongs},
+		{"CanceledContext", testCanceledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t))
+		})
+	}
+}
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		t.Fatalf("invalid date %q: %v", value, err)
+	}
+	return d
+}
+
+func addSong(t *testing.T, s storage.Storage, group, name, releaseDate string, verses ...string) uint {
+	t.Helper()
+	id, err := s.AddSong(ctx, model.Song{
+		Group:       group,
+		Name:        name,
+		Link:        "https://example.com/" + name,
+		ReleaseDate: date(t, releaseDate),
+	}, verses)
+	if err != nil {
+		t.Fatalf("AddSong(%q, %q): %v", group, name, err)
+	}
+	return id
+}
+
+func songIDs(songs []model.Song) []uint {
+	ids := make([]uint, 0, len(songs))
+	for _, song := range songs {
+		ids = append(ids, song.ID)
+	}
+	return ids
+}
+
+func verseTexts(lyrics []model.Lyrics) []string {
+	texts := make([]string, 0, len(lyrics))
+	for _, lyric := range lyrics {
+		texts = append(texts, lyric.Text)
+	}
+	return texts
+}
+
+func equal[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameSet[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[T]int, len(a))
+	for _, v := range a {
+		seen[v]++
+	}
+	for _, v := range b {
+		if seen[v] == 0 {
+			return false
+		}
+		seen[v]--
+	}
+	return true
+}
+
+func testAddAndGetSong(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "The paranoia is in bloom")
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.ID != id || song.Group != "Muse" || song.Name != "Uprising" {
+		t.Errorf("GetSong = %+v, want id %d Muse/Uprising", song, id)
+	}
+	if song.Link != "https://example.com/Uprising" {
+		t.Errorf("Link = %q", song.Link)
+	}
+	if got := song.ReleaseDate.Format(dateLayout); got != "2009-09-07" {
+		t.Errorf("ReleaseDate = %s, want 2009-09-07", got)
+	}
+	if song.InsertedAt.IsZero() {
+		t.Error("InsertedAt is not set")
+	}
+}
+
+func testAddSongDuplicate(t *testing.T, s storage.Storage) {
+	addSong(t, s, "Muse", "Resistance", "2009-09-14")
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1")
+
+	_, err := s.AddSong(ctx, model.Song{
+		Group:  "Muse",
+		Name:   "Uprising",
+		Status: model.SongStatusPendingEnrichment,
+	}, []string{"other"})
+	var exists *storage.ErrSongExists
+	if !errors.As(err, &exists) {
+		t.Fatalf("AddSong with duplicate group and name error = %v, want ErrSongExists", err)
+	}
+	if exists.ID != id {
+		t.Errorf("ErrSongExists.ID = %d, want %d", exists.ID, id)
+	}
+
+	// the failed insert must leave nothing behind
+	assertNoDueJob(t, s, time.Now().Add(time.Hour))
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if got := verseTexts(lyrics); !equal(got, []string{"v1"}) {
+		t.Errorf("verses = %q, want [v1]", got)
+	}
+
+	addSong(t, s, "Placebo", "Uprising", "2009-09-14")
+}
+
+func testGetSongNotFound(t *testing.T, s storage.Storage) {
+	if _, err := s.GetSong(ctx, 42); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("GetSong(missing) error = %v, want ErrSongNotFound", err)
+	}
+}
+
+func testVerseOrdering(t *testing.T, s storage.Storage) {
+	verses := []string{"first", "second", "third", "fourth"}
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", verses...)
+	addSong(t, s, "Muse", "Resistance", "2009-09-14", "other")
+
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if !equal(verseTexts(lyrics), verses) {
+		t.Fatalf("verses = %q, want %q", verseTexts(lyrics), verses)
+	}
+	for i, lyric := range lyrics {
+		if lyric.SongID != id {
+			t.Errorf("verse %d SongID = %d, want %d", i, lyric.SongID, id)
+		}
+		if lyric.VerseNumber != uint(i+1) {
+			t.Errorf("verse %d VerseNumber = %d, want %d", i, lyric.VerseNumber, i+1)
+		}
+	}
+
+	empty := addSong(t, s, "Muse", "Instrumental", "2009-09-21")
+	lyrics, err = s.GetAllSongLyrics(ctx, empty)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics(no verses): %v", err)
+	}
+	if len(lyrics) != 0 {
+		t.Errorf("GetAllSongLyrics(no verses) = %v, want none", lyrics)
+	}
+}
+
+func testGetLyricsPagination(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2", "v3", "v4", "v5")
+
+	tests := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{10, 0, []string{"v1", "v2", "v3", "v4", "v5"}},
+		{2, 0, []string{"v1", "v2"}},
+		{2, 2, []string{"v3", "v4"}},
+		{2, 4, []string{"v5"}},
+		{3, 5, []string{}},
+		{0, 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		lyrics, err := s.GetLyrics(ctx, id, tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("GetLyrics(%d, %d): %v", tt.limit, tt.offset, err)
+		}
+		if got := verseTexts(lyrics); !equal(got, tt.want) {
+			t.Errorf("GetLyrics(%d, %d) = %q, want %q", tt.limit, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func testGetLyricsForSongs(t *testing.T, s storage.Storage) {
+	a := addSong(t, s, "Muse", "Uprising", "2009-09-07", "a1", "a2", "a3")
+	b := addSong(t, s, "Muse", "Resistance", "2009-09-14", "b1")
+	empty := addSong(t, s, "Muse", "Instrumental", "2009-09-21")
+	skipped := addSong(t, s, "Muse", "Exogenesis", "2009-09-28", "c1")
+
+	lyrics, err := s.GetLyricsForSongs(ctx, []uint{b, a, empty, 42})
+	if err != nil {
+		t.Fatalf("GetLyricsForSongs: %v", err)
+	}
+
+	if got := verseTexts(lyrics[a]); !equal(got, []string{"a1", "a2", "a3"}) {
+		t.Errorf("verses of %d = %q, want [a1 a2 a3]", a, got)
+	}
+	for i, lyric := range lyrics[a] {
+		if lyric.SongID != a || lyric.VerseNumber != uint(i+1) {
+			t.Errorf("verse %d = %+v", i, lyric)
+		}
+	}
+	if got := verseTexts(lyrics[b]); !equal(got, []string{"b1"}) {
+		t.Errorf("verses of %d = %q, want [b1]", b, got)
+	}
+	if len(lyrics[empty]) != 0 || len(lyrics[42]) != 0 {
+		t.Errorf("unexpected verses for songs without lyrics: %v", lyrics)
+	}
+	if _, ok := lyrics[skipped]; ok {
+		t.Errorf("verses of song %d were not requested", skipped)
+	}
+
+	lyrics, err = s.GetLyricsForSongs(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetLyricsForSongs(nil): %v", err)
+	}
+	if len(lyrics) != 0 {
+		t.Errorf("GetLyricsForSongs(nil) = %v, want empty", lyrics)
+	}
+}
+
+func testGetAllSongsFilters(t *testing.T, s storage.Storage) {
+	uprising := addSong(t, s, "Muse", "Uprising", "2009-09-07", "They will not force us")
+	resistance := addSong(t, s, "Muse", "Resistance", "2009-09-14")
+	placebo := addSong(t, s, "Placebo", "Every You Every Me", "1998-08-10", "Sucker love")
+	museum := addSong(t, s, "The Museum", "Rising Sun", "2009-09-07")
+	unlinked, err := s.AddSong(ctx, model.Song{
+		Group:       "Muse",
+		Name:        "Hysteria",
+		ReleaseDate: date(t, "2003-12-01"),
+	}, []string{"It's bugging me"})
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+
+	day := func(value string) *time.Time {
+		d := date(t, value)
+		return &d
+	}
+	yes, no := true, false
+	past := time.Now().Add(-24 * time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+	// instants an hour away in a zone more than an hour off UTC, so comparing
+	// wall clocks instead of instants excludes every song
+	hourAgoEast := time.Now().Add(-time.Hour).In(time.FixedZone("UTC+3", 3*60*60))
+	inHourWest := time.Now().Add(time.Hour).In(time.FixedZone("UTC-3", -3*60*60))
+
+	tests := []struct {
+		name   string
+		filter model.SongFilter
+		want   []uint
+	}{
+		{"none", model.SongFilter{}, []uint{uprising, resistance, placebo, museum, unlinked}},
+		{"group substring", model.SongFilter{Group: "muse"}, []uint{uprising, resistance, museum, unlinked}},
+		{"group case insensitive", model.SongFilter{Group: "PLACEBO"}, []uint{placebo}},
+		{"group exact", model.SongFilter{Group: "Muse", GroupExact: true}, []uint{uprising, resistance, unlinked}},
+		{"group exact no substring", model.SongFilter{Group: "Mus", GroupExact: true}, []uint{}},
+		{"name substring", model.SongFilter{Name: "ris"}, []uint{uprising, museum}},
+		{"release date", model.SongFilter{ReleaseDate: day("2009-09-07")}, []uint{uprising, museum}},
+		{"group and name", model.SongFilter{Group: "muse", Name: "sist"}, []uint{resistance}},
+		{"group and release date", model.SongFilter{Group: "the", ReleaseDate: day("2009-09-07")}, []uint{museum}},
+		{"ids", model.SongFilter{IDs: []uint{placebo, museum, 9999}}, []uint{placebo, museum}},
+		{"ids and group", model.SongFilter{IDs: []uint{placebo, museum}, Group: "muse"}, []uint{museum}},
+		{"release date from", model.SongFilter{ReleaseDateFrom: day("2009-09-07")}, []uint{uprising, resistance, museum}},
+		{"release date to", model.SongFilter{ReleaseDateTo: day("2009-09-07")}, []uint{uprising, placebo, museum, unlinked}},
+		{"release date range", model.SongFilter{
+			ReleaseDateFrom: day("2000-01-01"),
+			ReleaseDateTo:   day("2009-09-07"),
+		}, []uint{uprising, museum, unlinked}},
+		{"inserted after", model.SongFilter{InsertedAfter: &past}, []uint{uprising, resistance, placebo, museum, unlinked}},
+		{"inserted after future", model.SongFilter{InsertedAfter: &future}, []uint{}},
+		{"inserted before", model.SongFilter{InsertedBefore: &past}, []uint{}},
+		{"inserted range", model.SongFilter{InsertedAfter: &past, InsertedBefore: &future}, []uint{uprising, resistance, placebo, museum, unlinked}},
+		{"inserted after with offset", model.SongFilter{InsertedAfter: &hourAgoEast}, []uint{uprising, resistance, placebo, museum, unlinked}},
+		{"inserted before with offset", model.SongFilter{InsertedBefore: &inHourWest}, []uint{uprising, resistance, placebo, museum, unlinked}},
+		{"has link", model.SongFilter{HasLink: &yes}, []uint{uprising, resistance, placebo, museum}},
+		{"has no link", model.SongFilter{HasLink: &no}, []uint{unlinked}},
+		{"has lyrics", model.SongFilter{HasLyrics: &yes}, []uint{uprising, placebo, unlinked}},
+		{"has no lyrics", model.SongFilter{HasLyrics: &no}, []uint{resistance, museum}},
+		{"has lyrics and link", model.SongFilter{HasLyrics: &yes, HasLink: &yes, Group: "muse"}, []uint{uprising}},
+		{"no match", model.SongFilter{Group: "muse", Name: "every"}, []uint{}},
+	}
+
+	for _, tt := range tests {
+		songs, err := s.GetAllSongs(ctx, tt.filter, nil, nil, 0, 0)
+		if err != nil {
+			t.Fatalf("%s: GetAllSongs: %v", tt.name, err)
+		}
+		if got := songIDs(songs); !sameSet(got, tt.want) {
+			t.Errorf("%s: GetAllSongs = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testGetAllSongsPagination(t *testing.T, s storage.Storage) {
+	c := addSong(t, s, "C", "Third", "2003-01-01")
+	a := addSong(t, s, "A", "First", "2001-01-01")
+	d := addSong(t, s, "D", "Fourth", "2004-01-01")
+	b := addSong(t, s, "B", "Second", "2002-01-01")
+
+	tests := []struct {
+		limit, offset int
+		want          []uint
+	}{
+		{10, 0, []uint{a, b, c, d}},
+		{2, 0, []uint{a, b}},
+		{2, 2, []uint{c, d}},
+		{3, 3, []uint{d}},
+		{2, 4, []uint{}},
+	}
+
+	for _, tt := range tests {
+		songs, err := s.GetAllSongs(ctx, model.SongFilter{}, nil, nil, tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("GetAllSongs(%d, %d): %v", tt.limit, tt.offset, err)
+		}
+		if got := songIDs(songs); !equal(got, tt.want) {
+			t.Errorf("GetAllSongs(%d, %d) = %v, want %v", tt.limit, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func testCountSongs(t *testing.T, s storage.Storage) {
+	addSong(t, s, "Muse", "Uprising", "2009-09-07", "verse")
+	addSong(t, s, "Muse", "Resistance", "2009-09-14")
+	addSong(t, s, "Placebo", "Every You Every Me", "1998-08-10")
+
+	yes := true
+	tests := []struct {
+		name   string
+		filter model.SongFilter
+		want   int
+	}{
+		{"none", model.SongFilter{}, 3},
+		{"group", model.SongFilter{Group: "muse"}, 2},
+		{"group and lyrics", model.SongFilter{Group: "muse", HasLyrics: &yes}, 1},
+		{"no match", model.SongFilter{Name: "nothing"}, 0},
+	}
+
+	for _, tt := range tests {
+		count, err := s.CountSongs(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: CountSongs: %v", tt.name, err)
+		}
+		if count != tt.want {
+			t.Errorf("%s: CountSongs = %d, want %d", tt.name, count, tt.want)
+		}
+	}
+}
+
+func testCountLyrics(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "one", "two", "three")
+	empty := addSong(t, s, "Muse", "Resistance", "2009-09-14")
+
+	for songID, want := range map[uint]int{id: 3, empty: 0, 9999: 0} {
+		count, err := s.CountLyrics(ctx, songID)
+		if err != nil {
+			t.Fatalf("CountLyrics(%d): %v", songID, err)
+		}
+		if count != want {
+			t.Errorf("CountLyrics(%d) = %d, want %d", songID, count, want)
+		}
+	}
+}
+
+func testGetAllSongsCursor(t *testing.T, s storage.Storage) {
+	b1 := addSong(t, s, "B", "First", "2002-01-01")
+	a := addSong(t, s, "A", "Only", "2001-01-01")
+	b2 := addSong(t, s, "B", "Second", "2002-01-01")
+	c := addSong(t, s, "C", "Only", "2003-01-01")
+	b3 := addSong(t, s, "B", "Third", "2002-01-01")
+
+	var got []uint
+	var after *model.SongCursor
+	for page := 0; page < 10; page++ {
+		songs, err := s.GetAllSongs(ctx, model.SongFilter{}, nil, after, 2, 0)
+		if err != nil {
+			t.Fatalf("GetAllSongs(page %d): %v", page, err)
+		}
+		if len(songs) == 0 {
+			break
+		}
+		got = append(got, songIDs(songs)...)
+
+		cursor := model.CursorFromSong(songs[len(songs)-1])
+		after = &cursor
+	}
+
+	want := []uint{a, b1, b2, b3, c}
+	if !equal(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+
+	songs, err := s.GetAllSongs(ctx, model.SongFilter{Group: "b"}, nil,
+		&model.SongCursor{ReleaseDate: date(t, "2002-01-01"), ID: b1}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAllSongs(filtered): %v", err)
+	}
+	if got := songIDs(songs); !equal(got, []uint{b2, b3}) {
+		t.Errorf("GetAllSongs(filtered) = %v, want %v", got, []uint{b2, b3})
+	}
+}
+
+func testGetAllSongsSort(t *testing.T, s storage.Storage) {
+	// inserted in this order, so inserted_at and ID grow together
+	c := addSong(t, s, "beta", "charlie", "2002-01-01")
+	a := addSong(t, s, "alpha", "alpha", "2003-01-01")
+	d := addSong(t, s, "beta", "delta", "2001-01-01")
+	b := addSong(t, s, "alpha", "bravo", "2002-01-01")
+
+	tests := []struct {
+		name string
+		sort []model.SortKey
+		want []uint
+	}{
+		{"default", nil, []uint{d, c, b, a}},
+		{"release date desc", []model.SortKey{{Field: model.SortByReleaseDate, Desc: true}}, []uint{a, c, b, d}},
+		{"name", []model.SortKey{{Field: model.SortByName}}, []uint{a, b, c, d}},
+		{"name desc", []model.SortKey{{Field: model.SortByName, Desc: true}}, []uint{d, c, b, a}},
+		{"group then name desc", []model.SortKey{
+			{Field: model.SortByGroup},
+			{Field: model.SortByName, Desc: true},
+		}, []uint{b, a, d, c}},
+		{"release date then group desc", []model.SortKey{
+			{Field: model.SortByReleaseDate},
+			{Field: model.SortByGroup, Desc: true},
+		}, []uint{d, c, b, a}},
+		{"inserted at desc", []model.SortKey{{Field: model.SortByInsertedAt, Desc: true}, {Field: model.SortByName}}, nil},
+	}
+
+	for _, tt := range tests {
+		songs, err := s.GetAllSongs(ctx, model.SongFilter{}, tt.sort, nil, 10, 0)
+		if err != nil {
+			t.Fatalf("%s: GetAllSongs: %v", tt.name, err)
+		}
+		got := songIDs(songs)
+		if tt.want == nil {
+			// inserted_at may have a coarse resolution, only check the order
+			for i := 1; i < len(songs); i++ {
+				if songs[i].InsertedAt.After(songs[i-1].InsertedAt) {
+					t.Errorf("%s: songs are not ordered by inserted_at desc: %v", tt.name, got)
+				}
+			}
+			continue
+		}
+		if !equal(got, tt.want) {
+			t.Errorf("%s: GetAllSongs = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testGetAllSongsSortCursor(t *testing.T, s storage.Storage) {
+	var ids []uint
+	for _, song := range []struct{ group, name, date string }{
+		{"beta", "one", "2001-01-01"},
+		{"alpha", "two", "2002-01-01"},
+		{"beta", "three", "2002-01-01"},
+		{"alpha", "four", "2001-01-01"},
+		{"beta", "five", "2002-01-01"},
+		{"alpha", "six", "2003-01-01"},
+	} {
+		ids = append(ids, addSong(t, s, song.group, song.name, song.date))
+	}
+
+	for _, sort := range [][]model.SortKey{
+		nil,
+		{{Field: model.SortByGroup, Desc: true}, {Field: model.SortByReleaseDate}},
+		{{Field: model.SortByReleaseDate, Desc: true}, {Field: model.SortByName}},
+		{{Field: model.SortByInsertedAt}},
+		{{Field: model.SortByInsertedAt, Desc: true}},
+	} {
+		all, err := s.GetAllSongs(ctx, model.SongFilter{}, sort, nil, 10, 0)
+		if err != nil {
+			t.Fatalf("GetAllSongs(%v): %v", sort, err)
+		}
+		if len(all) != len(ids) {
+			t.Fatalf("GetAllSongs(%v) returned %d songs, want %d", sort, len(all), len(ids))
+		}
+
+		var paged []uint
+		var after *model.SongCursor
+		for page := 0; page < 10; page++ {
+			songs, err := s.GetAllSongs(ctx, model.SongFilter{}, sort, after, 2, 0)
+			if err != nil {
+				t.Fatalf("GetAllSongs(%v, page %d): %v", sort, page, err)
+			}
+			if len(songs) == 0 {
+				break
+			}
+			paged = append(paged, songIDs(songs)...)
+			cursor := model.CursorFromSong(songs[len(songs)-1])
+			after = &cursor
+		}
+
+		if want := songIDs(all); !equal(paged, want) {
+			t.Errorf("sort %v: pages = %v, want %v", sort, paged, want)
+		}
+	}
+}
+
+func testGetAllSongsInvalidSort(t *testing.T, s storage.Storage) {
+	addSong(t, s, "Muse", "Uprising", "2009-09-07")
+
+	for _, sort := range [][]model.SortKey{
+		{{Field: "link"}},
+		{{Field: "id; DROP TABLE songs"}},
+		{{Field: model.SortByName}, {Field: model.SortByName, Desc: true}},
+	} {
+		if _, err := s.GetAllSongs(ctx, model.SongFilter{}, sort, nil, 10, 0); !errors.Is(err, storage.ErrInvalidSort) {
+			t.Errorf("GetAllSongs(%v) error = %v, want ErrInvalidSort", sort, err)
+		}
+	}
+}
+
+func testGetAllSongsUnknownReleaseDate(t *testing.T, s storage.Storage) {
+	pending := addPendingSong(t, s, "Muse", "Resistance")
+	older := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+	newer := addSong(t, s, "Muse", "Madness", "2012-08-20")
+
+	// songs without a release date yet come last in both directions, also
+	// across keyset pages
+	for _, tt := range []struct {
+		sort []model.SortKey
+		want []uint
+	}{
+		{nil, []uint{older, newer, pending}},
+		{[]model.SortKey{{Field: model.SortByReleaseDate, Desc: true}}, []uint{newer, older, pending}},
+	} {
+		var paged []uint
+		var after *model.SongCursor
+		for page := 0; page < 5; page++ {
+			songs, err := s.GetAllSongs(ctx, model.SongFilter{}, tt.sort, after, 1, 0)
+			if err != nil {
+				t.Fatalf("GetAllSongs(%v, page %d): %v", tt.sort, page, err)
+			}
+			if len(songs) == 0 {
+				break
+			}
+			paged = append(paged, songIDs(songs)...)
+			cursor := model.CursorFromSong(songs[len(songs)-1])
+			after = &cursor
+		}
+		if !equal(paged, tt.want) {
+			t.Errorf("sort %v: pages = %v, want %v", tt.sort, paged, tt.want)
+		}
+	}
+
+	before := date(t, "2010-01-01")
+	for _, filter := range []model.SongFilter{
+		{ReleaseDateTo: &before},
+		{ReleaseDateFrom: &time.Time{}, ReleaseDateTo: &before},
+	} {
+		songs, err := s.GetAllSongs(ctx, filter, nil, nil, 0, 0)
+		if err != nil {
+			t.Fatalf("GetAllSongs: %v", err)
+		}
+		if got := songIDs(songs); !equal(got, []uint{older}) {
+			t.Errorf("GetAllSongs(%+v) = %v, want only [%d]", filter, got, older)
+		}
+		if count, err := s.CountSongs(ctx, filter); err != nil || count != 1 {
+			t.Errorf("CountSongs(%+v) = %d, %v, want 1", filter, count, err)
+		}
+	}
+}
+
+func testDeleteSongCascade(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2")
+	other := addSong(t, s, "Muse", "Resistance", "2009-09-14", "r1")
+
+	if err := s.DeleteSong(ctx, id); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	if _, err := s.GetSong(ctx, id); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("GetSong(deleted) error = %v, want ErrSongNotFound", err)
+	}
+
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics(deleted): %v", err)
+	}
+	if len(lyrics) != 0 {
+		t.Errorf("lyrics of deleted song = %v, want none", lyrics)
+	}
+
+	lyrics, err = s.GetAllSongLyrics(ctx, other)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics(other): %v", err)
+	}
+	if !equal(verseTexts(lyrics), []string{"r1"}) {
+		t.Errorf("lyrics of other song = %q, want [r1]", verseTexts(lyrics))
+	}
+
+	if err := s.DeleteSong(ctx, id); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("DeleteSong(deleted) error = %v, want ErrSongNotFound", err)
+	}
+
+	// the song keeps its name in the trash until it is purged
+	_, err = s.AddSong(ctx, model.Song{Group: "Muse", Name: "Uprising"}, nil)
+	var exists *storage.ErrSongExists
+	if !errors.As(err, &exists) || exists.ID != id || !exists.Deleted {
+		t.Fatalf("AddSong(deleted) error = %v, want ErrSongExists for the deleted song", err)
+	}
+	if _, err := s.PurgeDeletedSongs(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("PurgeDeletedSongs: %v", err)
+	}
+	addSong(t, s, "Muse", "Uprising", "2009-09-07")
+}
+
+func testDeleteSongNotFound(t *testing.T, s storage.Storage) {
+	if err := s.DeleteSong(ctx, 42); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("DeleteSong(missing) error = %v, want ErrSongNotFound", err)
+	}
+}
+
+func testUpdateSongPartial(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1")
+
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Name: "Uprising (Live)"}); err != nil {
+		t.Fatalf("UpdateSong(name): %v", err)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Name != "Uprising (Live)" {
+		t.Errorf("Name = %q, want updated", song.Name)
+	}
+	if song.Group != "Muse" || song.Link != "https://example.com/Uprising" {
+		t.Errorf("untouched fields changed: %+v", song)
+	}
+	if got := song.ReleaseDate.Format(dateLayout); got != "2009-09-07" {
+		t.Errorf("ReleaseDate = %s, want unchanged", got)
+	}
+
+	err = s.UpdateSong(ctx, id, model.SongUpdate{
+		Group:       "MUSE",
+		ReleaseDate: "2010-01-02",
+		Link:        "https://example.com/live",
+	})
+	if err != nil {
+		t.Fatalf("UpdateSong(fields): %v", err)
+	}
+
+	song, err = s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Group != "MUSE" || song.Name != "Uprising (Live)" || song.Link != "https://example.com/live" {
+		t.Errorf("GetSong after update = %+v", song)
+	}
+	if got := song.ReleaseDate.Format(dateLayout); got != "2010-01-02" {
+		t.Errorf("ReleaseDate = %s, want 2010-01-02", got)
+	}
+
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if !equal(verseTexts(lyrics), []string{"v1"}) {
+		t.Errorf("verses changed by field update: %q", verseTexts(lyrics))
+	}
+}
+
+func testUpdateSongVerses(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2", "v3")
+
+	err := s.UpdateSong(ctx, id, model.SongUpdate{Verses: map[uint]string{1: "new v1", 3: "new v3"}})
+	if err != nil {
+		t.Fatalf("UpdateSong(verses): %v", err)
+	}
+
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	want := []string{"new v1", "v2", "new v3"}
+	if !equal(verseTexts(lyrics), want) {
+		t.Errorf("verses = %q, want %q", verseTexts(lyrics), want)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Name != "Uprising" {
+		t.Errorf("Name changed by verse update: %q", song.Name)
+	}
+}
+
+func testUpdateSongEmpty(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{}); err == nil {
+		t.Error("UpdateSong with no fields succeeded")
+	}
+}
+
+func testUpdateSongNotFound(t *testing.T, s storage.Storage) {
+	err := s.UpdateSong(ctx, 42, model.SongUpdate{Name: "Missing"})
+	if !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("UpdateSong(missing) error = %v, want ErrSongNotFound", err)
+	}
+
+	err = s.UpdateSong(ctx, 42, model.SongUpdate{Verses: map[uint]string{1: "text"}})
+	if !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("UpdateSong(missing, verses) error = %v, want ErrSongNotFound", err)
+	}
+}
+
+func testUpdateSongNameTaken(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+	other := addSong(t, s, "Muse", "Resistance", "2009-09-14")
+	trashed := addSong(t, s, "Muse", "Exogenesis", "2009-09-14")
+	deleteSong(t, s, trashed)
+
+	err := s.UpdateSong(ctx, id, model.SongUpdate{Name: "Resistance", Link: "https://example.com/new"})
+	var exists *storage.ErrSongExists
+	if !errors.As(err, &exists) || exists.ID != other || exists.Deleted {
+		t.Fatalf("UpdateSong(taken name) error = %v, want ErrSongExists with id %d", err, other)
+	}
+
+	err = s.UpdateSong(ctx, id, model.SongUpdate{Name: "Exogenesis"})
+	if !errors.As(err, &exists) || exists.ID != trashed || !exists.Deleted {
+		t.Fatalf("UpdateSong(trashed name) error = %v, want ErrSongExists for the deleted song %d", err, trashed)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Name != "Uprising" || song.Link == "https://example.com/new" {
+		t.Errorf("song = %+v, want it unchanged", song)
+	}
+
+	// keeping its own name is not a conflict
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Group: "Muse", Name: "Uprising"}); err != nil {
+		t.Errorf("UpdateSong(same name): %v", err)
+	}
+}
+
+func testUpdateSongVerseNotFound(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2")
+
+	err := s.UpdateSong(ctx, id, model.SongUpdate{
+		Name:   "Uprising (Live)",
+		Verses: map[uint]string{1: "new v1", 5: "new v5"},
+	})
+	if !errors.Is(err, storage.ErrVerseNotFound) {
+		t.Fatalf("UpdateSong(missing verse) error = %v, want ErrVerseNotFound", err)
+	}
+
+	song, err := s.GetSong(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.Name != "Uprising" {
+		t.Errorf("Name = %q, failed update was partially applied", song.Name)
+	}
+
+	lyrics, err := s.GetAllSongLyrics(ctx, id)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if !equal(verseTexts(lyrics), []string{"v1", "v2"}) {
+		t.Errorf("verses = %q, failed update was partially applied", verseTexts(lyrics))
+	}
+}
+
+func assertVerses(t *testing.T, s storage.Storage, songID uint, want ...string) {
+	t.Helper()
+
+	lyrics, err := s.GetAllSongLyrics(ctx, songID)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if got := verseTexts(lyrics); !equal(got, want) {
+		t.Fatalf("verses = %q, want %q", got, want)
+	}
+	for i, lyric := range lyrics {
+		if lyric.VerseNumber != uint(i+1) {
+			t.Errorf("verse %q has number %d, want %d", lyric.Text, lyric.VerseNumber, i+1)
+		}
+	}
+}
+
+func testAddVerse(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2")
+	other := addSong(t, s, "Muse", "Resistance", "2009-09-14", "r1", "r2")
+
+	tests := []struct {
+		position   uint
+		text       string
+		wantNumber uint
+		want       []string
+	}{
+		{0, "appended", 3, []string{"v1", "v2", "appended"}},
+		{1, "first", 1, []string{"first", "v1", "v2", "appended"}},
+		{3, "middle", 3, []string{"first", "v1", "middle", "v2", "appended"}},
+		{6, "last", 6, []string{"first", "v1", "middle", "v2", "appended", "last"}},
+	}
+
+	for _, tt := range tests {
+		number, err := s.AddVerse(ctx, id, tt.position, tt.text)
+		if err != nil {
+			t.Fatalf("AddVerse(%d, %q): %v", tt.position, tt.text, err)
+		}
+		if number != tt.wantNumber {
+			t.Errorf("AddVerse(%d, %q) = %d, want %d", tt.position, tt.text, number, tt.wantNumber)
+		}
+		assertVerses(t, s, id, tt.want...)
+	}
+
+	assertVerses(t, s, other, "r1", "r2")
+
+	empty := addSong(t, s, "Muse", "Instrumental", "2009-09-21")
+	if _, err := s.AddVerse(ctx, empty, 1, "only"); err != nil {
+		t.Fatalf("AddVerse(no verses): %v", err)
+	}
+	assertVerses(t, s, empty, "only")
+}
+
+func testAddVerseInvalid(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2")
+
+	if _, err := s.AddVerse(ctx, id, 4, "too far"); !errors.Is(err, storage.ErrInvalidVersePosition) {
+		t.Errorf("AddVerse(past end) error = %v, want ErrInvalidVersePosition", err)
+	}
+	if _, err := s.AddVerse(ctx, 42, 0, "text"); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("AddVerse(missing song) error = %v, want ErrSongNotFound", err)
+	}
+
+	assertVerses(t, s, id, "v1", "v2")
+}
+
+func testDeleteVerse(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2", "v3", "v4")
+	other := addSong(t, s, "Muse", "Resistance", "2009-09-14", "r1", "r2")
+
+	if err := s.DeleteVerse(ctx, id, 2); err != nil {
+		t.Fatalf("DeleteVerse(2): %v", err)
+	}
+	assertVerses(t, s, id, "v1", "v3", "v4")
+
+	if err := s.DeleteVerse(ctx, id, 3); err != nil {
+		t.Fatalf("DeleteVerse(last): %v", err)
+	}
+	assertVerses(t, s, id, "v1", "v3")
+
+	if err := s.DeleteVerse(ctx, id, 1); err != nil {
+		t.Fatalf("DeleteVerse(first): %v", err)
+	}
+	assertVerses(t, s, id, "v3")
+
+	assertVerses(t, s, other, "r1", "r2")
+}
+
+func testDeleteVerseNotFound(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1")
+
+	if err := s.DeleteVerse(ctx, id, 2); !errors.Is(err, storage.ErrVerseNotFound) {
+		t.Errorf("DeleteVerse(missing verse) error = %v, want ErrVerseNotFound", err)
+	}
+	if err := s.DeleteVerse(ctx, 42, 1); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("DeleteVerse(missing song) error = %v, want ErrSongNotFound", err)
+	}
+
+	assertVerses(t, s, id, "v1")
+}
+
+func testReorderVerses(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2", "v3", "v4")
+	other := addSong(t, s, "Muse", "Resistance", "2009-09-14", "r1", "r2")
+
+	if err := s.ReorderVerses(ctx, id, []uint{4, 2, 1, 3}); err != nil {
+		t.Fatalf("ReorderVerses: %v", err)
+	}
+	assertVerses(t, s, id, "v4", "v2", "v1", "v3")
+
+	if err := s.ReorderVerses(ctx, id, []uint{1, 2, 3, 4}); err != nil {
+		t.Fatalf("ReorderVerses(identity): %v", err)
+	}
+	assertVerses(t, s, id, "v4", "v2", "v1", "v3")
+
+	assertVerses(t, s, other, "r1", "r2")
+}
+
+func testReorderVersesInvalid(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2", "v3")
+
+	for _, order := range [][]uint{
+		{1, 2},
+		{1, 2, 3, 4},
+		{1, 1, 2},
+		{0, 1, 2},
+		{1, 2, 4},
+	} {
+		if err := s.ReorderVerses(ctx, id, order); !errors.Is(err, storage.ErrInvalidVerseOrder) {
+			t.Errorf("ReorderVerses(%v) error = %v, want ErrInvalidVerseOrder", order, err)
+		}
+	}
+
+	if err := s.ReorderVerses(ctx, 42, []uint{1}); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("ReorderVerses(missing song) error = %v, want ErrSongNotFound", err)
+	}
+
+	assertVerses(t, s, id, "v1", "v2", "v3")
+}
+
+func testSearchLyrics(t *testing.T, s storage.Storage) {
+	radioactive := addSong(t, s, "Imagine Dragons", "Radioactive", "2012-07-02",
+		"I raise my flags, dye my clothes",
+		"It's a revolution, I suppose",
+		"Welcome to the new age")
+	sound := addSong(t, s, "The 1975", "The Sound", "2016-02-25",
+		"Well I know when you're around",
+		"Cause I know the sound of the beat of your heart")
+	addSong(t, s, "Muse", "Uprising", "2009-09-07", "The paranoia is in bloom")
+
+	results, err := s.SearchLyrics(ctx, "REVOLUTION", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchLyrics(word): %v", err)
+	}
+	if len(results) != 1 || results[0].Song.ID != radioactive {
+		t.Fatalf("SearchLyrics(word) = %+v, want only song %d", results, radioactive)
+	}
+	if results[0].Song.Name != "Radioactive" || results[0].Song.Group != "Imagine Dragons" {
+		t.Errorf("result song = %+v", results[0].Song)
+	}
+	if results[0].Rank <= 0 {
+		t.Errorf("Rank = %v, want positive", results[0].Rank)
+	}
+	if len(results[0].Verses) != 1 || results[0].Verses[0].VerseNumber != 2 {
+		t.Fatalf("matched verses = %+v, want verse 2", results[0].Verses)
+	}
+	if want := storage.HighlightStart + "revolution" + storage.HighlightStop; !strings.Contains(results[0].Verses[0].Text, want) {
+		t.Errorf("highlight = %q, want it to contain %q", results[0].Verses[0].Text, want)
+	}
+
+	results, err = s.SearchLyrics(ctx, "know", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchLyrics(two verses): %v", err)
+	}
+	if len(results) != 1 || results[0].Song.ID != sound || len(results[0].Verses) != 2 {
+		t.Fatalf("SearchLyrics(two verses) = %+v, want both verses of song %d", results, sound)
+	}
+	if results[0].Verses[0].VerseNumber != 1 || results[0].Verses[1].VerseNumber != 2 {
+		t.Errorf("verses are not ordered: %+v", results[0].Verses)
+	}
+
+	results, err = s.SearchLyrics(ctx, `"the sound of"`, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchLyrics(phrase): %v", err)
+	}
+	if len(results) != 1 || results[0].Song.ID != sound {
+		t.Errorf("SearchLyrics(phrase) = %+v, want song %d", results, sound)
+	}
+
+	results, err = s.SearchLyrics(ctx, `"sound the"`, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchLyrics(reversed phrase): %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("SearchLyrics(reversed phrase) = %+v, want none", results)
+	}
+
+	results, err = s.SearchLyrics(ctx, "new raise", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchLyrics(words in different verses): %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("SearchLyrics(words in different verses) = %+v, want none", results)
+	}
+
+	results, err = s.SearchLyrics(ctx, "missing", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchLyrics(no match): %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("SearchLyrics(no match) = %+v, want none", results)
+	}
+}
+
+func testSearchLyricsPagination(t *testing.T, s storage.Storage) {
+	once := addSong(t, s, "A", "Once", "2001-01-01", "love is all", "nothing else")
+	thrice := addSong(t, s, "B", "Thrice", "2002-01-01", "love me", "love you", "love us")
+	twice := addSong(t, s, "C", "Twice", "2003-01-01", "love me", "love you", "nothing else")
+	addSong(t, s, "D", "Never", "2004-01-01", "nothing at all")
+
+	tests := []struct {
+		limit, offset int
+		want          []uint
+	}{
+		{10, 0, []uint{thrice, twice, once}},
+		{2, 0, []uint{thrice, twice}},
+		{2, 2, []uint{once}},
+		{2, 4, []uint{}},
+	}
+
+	for _, tt := range tests {
+		results, err := s.SearchLyrics(ctx, "love", tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("SearchLyrics(%d, %d): %v", tt.limit, tt.offset, err)
+		}
+		got := make([]uint, 0, len(results))
+		for _, result := range results {
+			got = append(got, result.Song.ID)
+		}
+		if !equal(got, tt.want) {
+			t.Errorf("SearchLyrics(%d, %d) = %v, want %v", tt.limit, tt.offset, got, tt.want)
+		}
+	}
+}
+
+func testCanceledContext(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if _, err := s.GetSong(canceled, id); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetSong(canceled) error = %v, want context.Canceled", err)
+	}
+	if _, err := s.GetAllSongs(canceled, model.SongFilter{}, nil, nil, 10, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetAllSongs(canceled) error = %v, want context.Canceled", err)
+	}
+	if _, err := s.AddSong(canceled, model.Song{Group: "Muse", Name: "Resistance"}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("AddSong(canceled) error = %v, want context.Canceled", err)
+	}
+	if err := s.DeleteSong(canceled, id); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeleteSong(canceled) error = %v, want context.Canceled", err)
+	}
+
+	if _, err := s.GetSong(ctx, id); err != nil {
+		t.Errorf("song was modified by canceled calls: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package storagetest
+
+import (
+	"errors"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+	"testing"
+	"time"
+)
+
+func deleteSong(t *testing.T, s storage.Storage, id uint) {
+	t.Helper()
+	if err := s.DeleteSong(ctx, id); err != nil {
+		t.Fatalf("DeleteSong(%d): %v", id, err)
+	}
+}
+
+func testTrash(t *testing.T, s storage.Storage) {
+	first := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1", "v2")
+	second := addSong(t, s, "Muse", "Resistance", "2009-09-14")
+	addSong(t, s, "Muse", "Undisclosed Desires", "2009-11-16")
+
+	deleteSong(t, s, first)
+	time.Sleep(1100 * time.Millisecond) // SQLite stores deletion times in seconds
+	deleteSong(t, s, second)
+
+	songs, err := s.GetDeletedSongs(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetDeletedSongs: %v", err)
+	}
+	if got, want := songIDs(songs), []uint{second, first}; !equal(got, want) {
+		t.Fatalf("GetDeletedSongs = %v, want %v, most recently deleted first", got, want)
+	}
+	if songs[1].Name != "Uprising" || songs[1].DeletedAt == nil || songs[1].DeletedAt.IsZero() {
+		t.Errorf("deleted song = %+v, want the song with its deletion time", songs[1])
+	}
+
+	page, err := s.GetDeletedSongs(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("GetDeletedSongs: %v", err)
+	}
+	if got := songIDs(page); !equal(got, []uint{first}) {
+		t.Errorf("second page = %v, want [%d]", got, first)
+	}
+	count, err := s.CountDeletedSongs(ctx)
+	if err != nil {
+		t.Fatalf("CountDeletedSongs: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("CountDeletedSongs = %d, want 2", count)
+	}
+
+	if err := s.RestoreSong(ctx, first); err != nil {
+		t.Fatalf("RestoreSong: %v", err)
+	}
+	song, err := s.GetSong(ctx, first)
+	if err != nil {
+		t.Fatalf("GetSong(restored): %v", err)
+	}
+	if song.Name != "Uprising" || song.DeletedAt != nil {
+		t.Errorf("restored song = %+v", song)
+	}
+	lyrics, err := s.GetAllSongLyrics(ctx, first)
+	if err != nil {
+		t.Fatalf("GetAllSongLyrics: %v", err)
+	}
+	if got := verseTexts(lyrics); !equal(got, []string{"v1", "v2"}) {
+		t.Errorf("verses of restored song = %q, want [v1 v2]", got)
+	}
+
+	if count, err := s.CountDeletedSongs(ctx); err != nil || count != 1 {
+		t.Errorf("CountDeletedSongs = %d, %v, want 1", count, err)
+	}
+}
+
+func testTrashHidesSong(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "paranoia is in bloom")
+	other := addSong(t, s, "Muse", "Resistance", "2009-09-14", "paranoia")
+	deleteSong(t, s, id)
+
+	songs, err := s.GetAllSongs(ctx, model.SongFilter{}, nil, nil, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAllSongs: %v", err)
+	}
+	if got := songIDs(songs); !equal(got, []uint{other}) {
+		t.Errorf("GetAllSongs = %v, want [%d]", got, other)
+	}
+	if count, err := s.CountSongs(ctx, model.SongFilter{}); err != nil || count != 1 {
+		t.Errorf("CountSongs = %d, %v, want 1", count, err)
+	}
+
+	if lyrics, err := s.GetLyrics(ctx, id, 10, 0); err != nil || len(lyrics) != 0 {
+		t.Errorf("GetLyrics(deleted) = %v, %v, want none", lyrics, err)
+	}
+	if count, err := s.CountLyrics(ctx, id); err != nil || count != 0 {
+		t.Errorf("CountLyrics(deleted) = %d, %v, want 0", count, err)
+	}
+	lyrics, err := s.GetLyricsForSongs(ctx, []uint{id, other})
+	if err != nil {
+		t.Fatalf("GetLyricsForSongs: %v", err)
+	}
+	if _, ok := lyrics[id]; ok || len(lyrics[other]) != 1 {
+		t.Errorf("GetLyricsForSongs = %v, want only the verses of %d", lyrics, other)
+	}
+
+	results, err := s.SearchLyrics(ctx, "paranoia", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchLyrics: %v", err)
+	}
+	if len(results) != 1 || results[0].Song.ID != other {
+		t.Errorf("SearchLyrics = %+v, want only song %d", results, other)
+	}
+
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Link: "https://example.com/new"}); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("UpdateSong(deleted) = %v, want ErrSongNotFound", err)
+	}
+	if _, err := s.AddVerse(ctx, id, 0, "v"); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("AddVerse(deleted) = %v, want ErrSongNotFound", err)
+	}
+	if _, err := s.RefreshSong(ctx, id, model.SongEnrichment{}); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("RefreshSong(deleted) = %v, want ErrSongNotFound", err)
+	}
+	if _, err := s.GetSongRevisions(ctx, id, 10, 0); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("GetSongRevisions(deleted) = %v, want ErrSongNotFound", err)
+	}
+
+	// the enrichment of a deleted song waits until it is restored
+	pending := addPendingSong(t, s, "Muse", "Resistance (Live)")
+	deleteSong(t, s, pending)
+	assertNoDueJob(t, s, time.Now().Add(time.Hour))
+	if err := s.RestoreSong(ctx, pending); err != nil {
+		t.Fatalf("RestoreSong: %v", err)
+	}
+	if job := claimJob(t, s, time.Now().Add(time.Second)); job.SongID != pending {
+		t.Errorf("claimed job of song %d, want %d", job.SongID, pending)
+	}
+}
+
+func testRestoreSongNotFound(t *testing.T, s storage.Storage) {
+	if err := s.RestoreSong(ctx, 42); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("RestoreSong(missing) = %v, want ErrSongNotFound", err)
+	}
+
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+	if err := s.RestoreSong(ctx, id); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("RestoreSong(not deleted) = %v, want ErrSongNotFound", err)
+	}
+}
+
+func testPurgeDeletedSongs(t *testing.T, s storage.Storage) {
+	id := addSong(t, s, "Muse", "Uprising", "2009-09-07", "v1")
+	if err := s.UpdateSong(ctx, id, model.SongUpdate{Link: "https://example.com/new"}); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	kept := addSong(t, s, "Muse", "Resistance", "2009-09-14")
+	deleteSong(t, s, id)
+
+	purged, err := s.PurgeDeletedSongs(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeletedSongs: %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("PurgeDeletedSongs(an hour ago) = %d, want 0", purged)
+	}
+
+	purged, err = s.PurgeDeletedSongs(ctx, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("PurgeDeletedSongs: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("PurgeDeletedSongs = %d, want 1", purged)
+	}
+	if count, err := s.CountDeletedSongs(ctx); err != nil || count != 0 {
+		t.Errorf("CountDeletedSongs = %d, %v, want 0", count, err)
+	}
+	if err := s.RestoreSong(ctx, id); !errors.Is(err, storage.ErrSongNotFound) {
+		t.Errorf("RestoreSong(purged) = %v, want ErrSongNotFound", err)
+	}
+	if _, err := s.GetSong(ctx, kept); err != nil {
+		t.Errorf("GetSong(kept): %v", err)
+	}
+
+	// the group and name are free again, without the old verses and history
+	again := addSong(t, s, "Muse", "Uprising", "2009-09-07")
+	if count, err := s.CountLyrics(ctx, again); err != nil || count != 0 {
+		t.Errorf("CountLyrics = %d, %v, want 0", count, err)
+	}
+	if count, err := s.CountSongRevisions(ctx, again); err != nil || count != 0 {
+		t.Errorf("CountSongRevisions = %d, %v, want 0", count, err)
+	}
+}
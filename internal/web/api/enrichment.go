@@ -0,0 +1,42 @@
+package web
+
+import (
+	"errors"
+	"songs_lib/internal/storage"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Статус загрузки данных песни
+// @Description Статус фоновой загрузки ссылки, даты релиза и текста песни из внешнего API: статус песни, состояние задачи, число попыток и последняя ошибка
+// @ID get-enrichment
+// @Tags Songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Success 200 {object} dto.EnrichmentDTO
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/{id}/enrichment [get]
+func (h *SongsHandlers) GetEnrichment(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
+		})
+	}
+
+	enrichment, err := h.songService.GetEnrichment(c.UserContext(), uint(songID))
+	if err != nil {
+		if errors.Is(err, storage.ErrSongNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Song not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get enrichment status",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(enrichment)
+}
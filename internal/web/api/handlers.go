@@ -7,9 +7,9 @@ import (
 	"songs_lib/internal/model"
 	songService "songs_lib/internal/service"
 	"songs_lib/internal/storage"
-	web "songs_lib/internal/web/external"
 	"songs_lib/pkg/logger"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -21,91 +21,163 @@ import (
 
 type SongsHandlers struct {
 	songService songService.ISong
+	idempotency songService.IIdempotency
 	log         *slog.Logger
 	validate    *validator.Validate
-	externalAPI string
 }
 
 func NewSongsHandlers(log *slog.Logger,
 	songService songService.ISong,
-	externalAPI string,
+	idempotency songService.IIdempotency,
 ) *SongsHandlers {
 	return &SongsHandlers{
 		songService: songService,
+		idempotency: idempotency,
 		log:         log,
 		validate:    validator.New(),
-		externalAPI: externalAPI,
 	}
 }
 
 // @Summary Добавление песни
-// @Description Добавление песни с указаым названием и группой
+// @Description Добавление песни с указаым названием и группой. Дата релиза, ссылка и текст, переданные клиентом, имеют приоритет над данными внешнего API; недостающие загружаются в фоне. С enrich=false внешний API не вызывается
 // @ID add-song
 // @Tags Songs
 // @Accept  json
 // @Produce  json
 // @Param song body dto.CreateSongRequest true "Song"
-// @Success 201 {object} dto.CreateSongResponse
+// @Param enrich query bool false "Загружать недостающие данные из внешнего API, по умолчанию true"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повторный запрос с тем же ключом получает исходный ответ"
+// @Success 201 {object} dto.CreateSongResponse "Песня сохранена со всеми данными"
+// @Success 202 {object} dto.CreateSongResponse "Недостающие данные загружаются в фоне"
 // @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Песня уже существует, Location указывает на неё, или находится в корзине"
+// @Header 409 {string} Location "Адрес существующей песни"
+// @Failure 422 {object} map[string]interface{} "Ключ идемпотентности использован для другого запроса"
+// @Failure 503 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/song [post]
 func (h *SongsHandlers) AddSong(c *fiber.Ctx) error {
-	var req dto.CreateSongRequest
-	if err := c.BodyParser(&req); err != nil {
-		h.log.Debug("Failed to parse request body", logger.Err(err))
+	req, releaseDate, err := h.parseCreateSongRequest(c)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
 
+	status := model.SongStatusReady
+	var id uint
+	if c.QueryBool("enrich", true) {
+		id, status, err = h.songService.AddSongForEnrichment(c.UserContext(), req.Group, req.Name, req.Link, releaseDate, req.Text)
+	} else {
+		id, err = h.songService.AddSong(c.UserContext(), req.Group, req.Name, req.Link, releaseDate, req.Text)
+	}
+	if err != nil {
+		var exists *storage.ErrSongExists
+		if errors.As(err, &exists) {
+			return songConflict(c, exists)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to add song",
+		})
+	}
+
+	resp := dto.CreateSongResponse{
+		ID:     id,
+		Group:  req.Group,
+		Name:   req.Name,
+		Status: string(status),
+		Link:   req.Link,
+		Text:   req.Text,
+	}
+	if !releaseDate.IsZero() {
+		resp.ReleaseDate = &releaseDate
+	}
+
+	c.Location(songLocation(id))
+	if status == model.SongStatusPendingEnrichment {
+		return c.Status(fiber.StatusAccepted).JSON(resp)
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// parseCreateSongRequest parses and validates the body of a request that
+// creates a song.
+func (h *SongsHandlers) parseCreateSongRequest(c *fiber.Ctx) (dto.CreateSongRequest, time.Time, error) {
+	var req dto.CreateSongRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Debug("Failed to parse request body", logger.Err(err))
+		return req, time.Time{}, err
+	}
+
 	if err := h.validate.Struct(req); err != nil {
 		h.log.Debug("Failed to validate request body", logger.Err(err))
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return req, time.Time{}, err
+	}
+
+	var releaseDate time.Time
+	if req.ReleaseDate != "" {
+		// the format is checked by the validator
+		releaseDate, _ = time.Parse("2006-01-02", req.ReleaseDate)
 	}
+	return req, releaseDate, nil
+}
 
-	fetchData, err := web.FetchSong(h.externalAPI, req.Group, req.Name)
+// @Summary Получение песни
+// @Description Получение песни со всеми куплетами
+// @ID get-song
+// @Tags Songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Success 200 {object} dto.SongDTO
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/{id} [get]
+func (h *SongsHandlers) GetSong(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		h.log.Debug("Failed to fetch song data", logger.Err(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch song data",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
 		})
 	}
 
-	releaseDate, err := time.Parse("02.01.2006", fetchData.ReleaseDate)
+	song, err := h.songService.GetSong(c.UserContext(), uint(songID))
 	if err != nil {
-		h.log.Debug("Failed to parse release date", logger.Err(err))
+		if errors.Is(err, storage.ErrSongNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Song not found",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to parse release date",
+			"error": "Failed to get song",
 		})
 	}
+	return c.Status(fiber.StatusOK).JSON(song)
+}
 
-	id, err := h.songService.AddSong(
-		req.Group,
-		req.Name,
-		fetchData.Link,
-		releaseDate,
-		fetchData.Text,
-	)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to add song",
+func songLocation(songID uint) string {
+	return "/api/v1/song/" + strconv.FormatUint(uint64(songID), 10)
+}
+
+// songConflict responds that a song with the same group and name exists. A
+// song in the trash can't be fetched, so there is no Location for it.
+func songConflict(c *fiber.Ctx, exists *storage.ErrSongExists) error {
+	if exists.Deleted {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Song is in the trash",
+			"id":    exists.ID,
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(dto.CreateSongResponse{
-		ID:          id,
-		Group:       req.Group,
-		Name:        req.Name,
-		ReleaseDate: releaseDate,
-		Link:        fetchData.Link,
-		Text:        fetchData.Text,
+	c.Location(songLocation(exists.ID))
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": "Song already exists",
+		"id":    exists.ID,
 	})
 }
 
 // @Summary Удаление песни
-// @Description Удалене песни по id
+// @Description Перемещает песню в корзину. Из корзины её можно восстановить, пока она не удалена окончательно по истечении срока хранения
 // @ID delete-song
 // @Tags Songs
 // @Accept  json
@@ -113,6 +185,7 @@ func (h *SongsHandlers) AddSong(c *fiber.Ctx) error {
 // @Param id path int true "Song id"
 // @Success 204 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/song/{id} [delete]
 func (h *SongsHandlers) DeleteSong(c *fiber.Ctx) error {
@@ -124,7 +197,7 @@ func (h *SongsHandlers) DeleteSong(c *fiber.Ctx) error {
 			"error": "Invalid song ID",
 		})
 	}
-	if err := h.songService.DeleteSong((uint)(songID)); err != nil {
+	if err := h.songService.DeleteSong(c.UserContext(), (uint)(songID)); err != nil {
 		if errors.Is(err, storage.ErrSongNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Song not found",
@@ -145,7 +218,9 @@ func (h *SongsHandlers) DeleteSong(c *fiber.Ctx) error {
 // @Param id path int true "Song ID"
 // @Param limit query int false "Количество куплетов"
 // @Param offset query int false "Смещение для пагинации"
-// @Success 200 {array} dto.SongDTO
+// @Success 200 {object} dto.LyricsPageDTO
+// @Header 200 {integer} X-Total-Count "Общее количество куплетов"
+// @Header 200 {string} Link "Ссылки на первую, предыдущую, следующую и последнюю страницы"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/lyrics/{id} [get]
@@ -161,6 +236,7 @@ func (h *SongsHandlers) GetLyrics(c *fiber.Ctx) error {
 
 	queryParams := c.Queries()
 	lyrics, err := h.songService.GetLyrics(
+		c.UserContext(),
 		(uint)(songID),
 		queryParams["limit"],
 		queryParams["offset"],
@@ -175,6 +251,7 @@ func (h *SongsHandlers) GetLyrics(c *fiber.Ctx) error {
 			"error": "Failed to get lyrics",
 		})
 	}
+	setPaginationHeaders(c, lyrics.PaginationDTO, "")
 	return c.Status(fiber.StatusOK).JSON(lyrics)
 }
 
@@ -188,6 +265,8 @@ func (h *SongsHandlers) GetLyrics(c *fiber.Ctx) error {
 // @Param updates body model.SongUpdate true "Updated Fields"
 // @Success 204 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Песня с такими группой и названием уже есть"
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/song/{id} [put]
 func (h *SongsHandlers) UpdateSong(c *fiber.Ctx) error {
@@ -199,6 +278,12 @@ func (h *SongsHandlers) UpdateSong(c *fiber.Ctx) error {
 			"error": "Invalid request body",
 		})
 	}
+	if err := h.validate.Struct(updates); err != nil {
+		h.log.Debug("Failed to validate request body", logger.Err(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid release date",
+		})
+	}
 
 	songID, err := strconv.Atoi(param)
 	if err != nil {
@@ -207,8 +292,22 @@ func (h *SongsHandlers) UpdateSong(c *fiber.Ctx) error {
 		})
 	}
 
-	_, err = h.songService.UpdateSong(uint(songID), updates)
+	_, err = h.songService.UpdateSong(c.UserContext(), uint(songID), updates)
 	if err != nil {
+		var exists *storage.ErrSongExists
+		if errors.As(err, &exists) {
+			return songConflict(c, exists)
+		}
+		if errors.Is(err, storage.ErrSongNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Song not found",
+			})
+		}
+		if errors.Is(err, storage.ErrVerseNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Verse not found",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update song",
 		})
@@ -222,29 +321,111 @@ func (h *SongsHandlers) UpdateSong(c *fiber.Ctx) error {
 // @ID get-library
 // @Tags Songs
 // @Param group query string false "Название группы"
+// @Param group_match query string false "Сравнение названия группы: fuzzy — по подстроке без учёта регистра (по умолчанию), exact — точное совпадение" Enums(fuzzy, exact)
 // @Param name query string false "Название песни"
-// @Param release_date query string false "Дата релиза"
+// @Param id query string false "ID песен через запятую, например 1,2,3"
+// @Param release_date query string false "Дата релиза в формате YYYY-MM-DD"
+// @Param release_date_from query string false "Дата релиза не раньше, YYYY-MM-DD"
+// @Param release_date_to query string false "Дата релиза не позже, YYYY-MM-DD"
+// @Param inserted_after query string false "Добавлены после момента, RFC 3339 или YYYY-MM-DD"
+// @Param inserted_before query string false "Добавлены до момента, RFC 3339 или YYYY-MM-DD"
+// @Param has_link query bool false "Только песни со ссылкой (true) или без неё (false)"
+// @Param has_lyrics query bool false "Только песни с текстом (true) или без него (false)"
 // @Param limit query int false "Количество записей на странице"
 // @Param offset query int false "Смещение для пагинации"
+// @Param sort query string false "Сортировка через запятую, минус перед полем — по убыванию. Поля: release_date, inserted_at, name, group. По умолчанию release_date"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor, выданный для той же сортировки"
+// @Param include query string false "Связанные данные через запятую: verses. Без параметра куплеты включаются, пустое значение возвращает только метаданные"
 // @Success 200 {object} dto.LibraryDTO
+// @Header 200 {integer} X-Total-Count "Общее количество песен, подходящих под фильтры"
+// @Header 200 {string} Link "Ссылки на первую, предыдущую, следующую и последнюю страницы"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/library [get]
 func (h *SongsHandlers) GetLibrary(c *fiber.Ctx) error {
 	queryParams := c.Queries()
+	filter, err := songService.ParseSongFilter(queryParams)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	library, err := h.songService.GetLibrary(
-		map[string]string{
-			"group":        queryParams["group"],
-			"name":         queryParams["name"],
-			"release_date": queryParams["release_date"],
-		},
+		c.UserContext(),
+		filter,
+		queryParams["sort"],
+		queryParams["cursor"],
 		queryParams["limit"],
 		queryParams["offset"],
+		includes(c, "verses"),
 	)
 	if err != nil {
+		if errors.Is(err, songService.ErrInvalidCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid cursor",
+			})
+		}
+		if errors.Is(err, storage.ErrInvalidSort) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid sort",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get library",
 		})
 	}
+	setPaginationHeaders(c, library.PaginationDTO, library.NextCursor)
 	return c.Status(fiber.StatusOK).JSON(library)
 }
+
+// @Summary Поиск по тексту песен
+// @Description Полнотекстовый поиск по куплетам: слова и фразы в кавычках, результаты отсортированы по релевантности, совпадения выделены тегом <b>
+// @ID search-lyrics
+// @Tags Lyrics
+// @Produce json
+// @Param q query string true "Поисковый запрос"
+// @Param limit query int false "Количество песен на странице"
+// @Param offset query int false "Смещение для пагинации"
+// @Success 200 {object} dto.SearchDTO
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/search [get]
+func (h *SongsHandlers) Search(c *fiber.Ctx) error {
+	queryParams := c.Queries()
+
+	query := strings.TrimSpace(queryParams["q"])
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Search query is required",
+		})
+	}
+
+	results, err := h.songService.Search(
+		c.UserContext(),
+		query,
+		queryParams["limit"],
+		queryParams["offset"],
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search lyrics",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(results)
+}
+
+// includes reports whether the comma separated include query parameter lists
+// the relation. Without the parameter every relation is included.
+func includes(c *fiber.Ctx, relation string) bool {
+	if c.Context().QueryArgs().Peek("include") == nil {
+		return true
+	}
+
+	for _, value := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(value) == relation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,588 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"songs_lib/config"
+	"songs_lib/internal/dto"
+	"songs_lib/internal/fakeinfo"
+	"songs_lib/internal/model"
+	"songs_lib/internal/service"
+	"songs_lib/internal/storage"
+	"songs_lib/internal/storage/memory"
+	external "songs_lib/internal/web/external"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type testAPI struct {
+	app           *fiber.App
+	enricher      *service.Enricher
+	upstreamCalls atomic.Int32
+}
+
+// newTestAPI serves the handlers over the memory storage, with the fake info
+// API as the upstream.
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	fixtures, err := fakeinfo.DefaultFixtures()
+	if err != nil {
+		t.Fatalf("DefaultFixtures: %v", err)
+	}
+	api := &testAPI{}
+	fake := fakeinfo.NewServer(fixtures, fakeinfo.Options{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.upstreamCalls.Add(1)
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(upstream.Close)
+
+	client := external.NewClient(log, config.ExternalAPI{URL: upstream.URL, Timeout: time.Second})
+	s := memory.NewMemoryStorage(log)
+
+	api.app = fiber.New()
+	SetupRoutes(api.app, NewSongsHandlers(log,
+		service.NewSongService(log, s, client),
+		service.NewIdempotencyService(log, s, config.Idempotency{}),
+	))
+	api.enricher = service.NewEnricher(log, s, client, config.Enrichment{})
+	return api
+}
+
+func (a *testAPI) do(t *testing.T, method, target, body string, out any) int {
+	t.Helper()
+	resp := a.request(t, method, target, body, out)
+	return resp.StatusCode
+}
+
+func (a *testAPI) request(t *testing.T, method, target, body string, out any, headers ...string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := a.app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, target, err)
+		}
+	}
+	return resp
+}
+
+func (a *testAPI) enrich(t *testing.T) {
+	t.Helper()
+	if found, err := a.enricher.ProcessNext(context.Background()); !found || err != nil {
+		t.Fatalf("ProcessNext = %v, %v", found, err)
+	}
+}
+
+func TestAddSongEnrichesFromUpstream(t *testing.T) {
+	api := newTestAPI(t)
+
+	var created dto.CreateSongResponse
+	status := api.do(t, http.MethodPost, "/api/v1/song", `{"group": "Muse", "name": "Supermassive Black Hole"}`, &created)
+	if status != http.StatusAccepted || created.Status != "pending_enrichment" {
+		t.Fatalf("POST /song = %d %+v", status, created)
+	}
+	api.enrich(t)
+
+	var lyrics dto.LyricsPageDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/lyrics/1", "", &lyrics); status != http.StatusOK {
+		t.Fatalf("GET /lyrics = %d", status)
+	}
+	if lyrics.Total != 2 || !strings.HasPrefix(lyrics.Lyrics[0].Text, "Ooh baby") {
+		t.Errorf("lyrics = %+v", lyrics)
+	}
+
+	var refresh dto.RefreshDTO
+	if status := api.do(t, http.MethodPost, "/api/v1/song/1/refresh", "", &refresh); status != http.StatusOK {
+		t.Fatalf("POST /refresh = %d", status)
+	}
+	if refresh.Changed {
+		t.Errorf("refresh = %+v, want no changes", refresh)
+	}
+}
+
+func TestRefreshSongUpstreamErrors(t *testing.T) {
+	api := newTestAPI(t)
+
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"Broken Upstream", http.StatusBadGateway},
+		{"Unknown", http.StatusUnprocessableEntity},
+	}
+	for i, tt := range tests {
+		body := `{"group": "Muse", "name": "` + tt.name + `"}`
+		if status := api.do(t, http.MethodPost, "/api/v1/song?enrich=false", body, nil); status != http.StatusCreated {
+			t.Fatalf("POST /song = %d", status)
+		}
+
+		target := "/api/v1/song/" + strconv.Itoa(i+1) + "/refresh"
+		if status := api.do(t, http.MethodPost, target, "", nil); status != tt.status {
+			t.Errorf("POST %s for %q = %d, want %d", target, tt.name, status, tt.status)
+		}
+	}
+
+	if status := api.do(t, http.MethodPost, "/api/v1/song/99/refresh", "", nil); status != http.StatusNotFound {
+		t.Errorf("POST /refresh of unknown song = %d, want 404", status)
+	}
+
+	// a song pending enrichment is left to its job
+	if status := api.do(t, http.MethodPost, "/api/v1/song", `{"group": "Muse", "name": "Uprising"}`, nil); status != http.StatusAccepted {
+		t.Fatalf("POST /song = %d", status)
+	}
+	calls := api.upstreamCalls.Load()
+	if status := api.do(t, http.MethodPost, "/api/v1/song/3/refresh", "", nil); status != http.StatusConflict {
+		t.Errorf("POST /refresh of pending song = %d, want 409", status)
+	}
+	if got := api.upstreamCalls.Load(); got != calls {
+		t.Errorf("upstream called %d times for the pending song", got-calls)
+	}
+	api.enrich(t)
+	if status := api.do(t, http.MethodPost, "/api/v1/song/3/refresh", "", nil); status != http.StatusOK {
+		t.Errorf("POST /refresh after enrichment = %d, want 200", status)
+	}
+}
+
+func TestAddSongManualMetadata(t *testing.T) {
+	api := newTestAPI(t)
+
+	body := `{"group": "Local Band", "name": "Demo", "release_date": "2020-05-01", "link": "https://example.com/demo", "text": "one\n\ntwo"}`
+	var created dto.CreateSongResponse
+	status := api.do(t, http.MethodPost, "/api/v1/song?enrich=false", body, &created)
+	if status != http.StatusCreated || created.Status != "ready" || created.ReleaseDate == nil {
+		t.Fatalf("POST /song?enrich=false = %d %+v", status, created)
+	}
+	if found, err := api.enricher.ProcessNext(context.Background()); found || err != nil {
+		t.Errorf("ProcessNext = %v, %v, want no job", found, err)
+	}
+
+	var lyrics dto.LyricsPageDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/lyrics/1", "", &lyrics); status != http.StatusOK || lyrics.Total != 2 {
+		t.Errorf("GET /lyrics = %d %+v", status, lyrics)
+	}
+
+	// without enrich=false only the missing details are fetched
+	body = `{"group": "Muse", "name": "Uprising", "link": "https://example.com/mine"}`
+	if status := api.do(t, http.MethodPost, "/api/v1/song", body, &created); status != http.StatusAccepted {
+		t.Fatalf("POST /song = %d", status)
+	}
+	api.enrich(t)
+
+	var library dto.LibraryDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/library?name=Uprising", "", &library); status != http.StatusOK {
+		t.Fatalf("GET /library = %d", status)
+	}
+	if len(library.Songs) != 1 || library.Songs[0].Link != "https://example.com/mine" || len(library.Songs[0].Lyrics) != 2 {
+		t.Errorf("library = %+v, want the client link and the fetched verses", library.Songs)
+	}
+
+	for _, body := range []string{
+		`{"group": "Muse", "name": "Uprising", "release_date": "07.09.2009"}`,
+		`{"group": "Muse", "name": "Uprising", "link": "not a link"}`,
+	} {
+		if status := api.do(t, http.MethodPost, "/api/v1/song?enrich=false", body, nil); status != http.StatusBadRequest {
+			t.Errorf("POST /song %s = %d, want 400", body, status)
+		}
+	}
+}
+
+func TestAddSongDuplicate(t *testing.T) {
+	api := newTestAPI(t)
+
+	body := `{"group": "Muse", "name": "Uprising"}`
+	resp := api.request(t, http.MethodPost, "/api/v1/song", body, nil)
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") != "/api/v1/song/1" {
+		t.Fatalf("POST /song = %d, Location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	api.enrich(t)
+	calls := api.upstreamCalls.Load()
+
+	var conflict struct {
+		ID uint `json:"id"`
+	}
+	resp = api.request(t, http.MethodPost, "/api/v1/song", `{"group": "Muse", "name": "Uprising", "link": "https://example.com"}`, &conflict)
+	if resp.StatusCode != http.StatusConflict || conflict.ID != 1 {
+		t.Fatalf("duplicate POST /song = %d %+v, want 409 with id 1", resp.StatusCode, conflict)
+	}
+	if location := resp.Header.Get("Location"); location != "/api/v1/song/1" {
+		t.Errorf("Location = %q, want /api/v1/song/1", location)
+	}
+
+	if found, err := api.enricher.ProcessNext(context.Background()); found || err != nil {
+		t.Errorf("ProcessNext = %v, %v, want no job for the duplicate", found, err)
+	}
+	if got := api.upstreamCalls.Load(); got != calls {
+		t.Errorf("upstream called %d more times for the duplicate", got-calls)
+	}
+
+	var song dto.SongDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1", "", &song); status != http.StatusOK {
+		t.Fatalf("GET /song/1 = %d", status)
+	}
+	if song.Name != "Uprising" || len(song.Lyrics) != 2 {
+		t.Errorf("song = %+v", song)
+	}
+	if status := api.do(t, http.MethodGet, "/api/v1/song/99", "", nil); status != http.StatusNotFound {
+		t.Errorf("GET /song/99 = %d, want 404", status)
+	}
+}
+
+func TestAddSongIdempotencyKey(t *testing.T) {
+	api := newTestAPI(t)
+
+	body := `{"group": "Muse", "name": "Uprising"}`
+	var first, replayed dto.CreateSongResponse
+	resp := api.request(t, http.MethodPost, "/api/v1/song", body, &first, HeaderIdempotencyKey, "key-1")
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get(HeaderIdempotentReplayed) != "" {
+		t.Fatalf("first POST /song = %d", resp.StatusCode)
+	}
+
+	resp = api.request(t, http.MethodPost, "/api/v1/song", body, &replayed, HeaderIdempotencyKey, "key-1")
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get(HeaderIdempotentReplayed) != "true" {
+		t.Fatalf("repeated POST /song = %d, replayed %q", resp.StatusCode, resp.Header.Get(HeaderIdempotentReplayed))
+	}
+	if replayed != first || resp.Header.Get("Location") != "/api/v1/song/1" {
+		t.Errorf("replayed = %+v, Location %q, want %+v", replayed, resp.Header.Get("Location"), first)
+	}
+
+	other := `{"group": "Muse", "name": "Resistance"}`
+	if status := api.request(t, http.MethodPost, "/api/v1/song", other, nil, HeaderIdempotencyKey, "key-1").StatusCode; status != http.StatusUnprocessableEntity {
+		t.Errorf("POST /song with a reused key = %d, want 422", status)
+	}
+
+	// without a key a retry is a duplicate
+	if status := api.do(t, http.MethodPost, "/api/v1/song", body, nil); status != http.StatusConflict {
+		t.Errorf("POST /song without a key = %d, want 409", status)
+	}
+}
+
+// timeoutStorage cancels the request context once the idempotency key is
+// reserved, as the HTTP timeout does to a slow request.
+type timeoutStorage struct {
+	storage.Storage
+	cancel context.CancelFunc
+}
+
+func (s *timeoutStorage) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, now, expiredBefore, staleBefore time.Time) (*model.IdempotencyRecord, error) {
+	record, err := s.Storage.ReserveIdempotencyKey(ctx, key, fingerprint, now, expiredBefore, staleBefore)
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return record, err
+}
+
+func TestIdempotencyKeyReleasedAfterTimeout(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &timeoutStorage{Storage: memory.NewMemoryStorage(log)}
+	client := external.NewClient(log, config.ExternalAPI{Timeout: time.Second})
+
+	timeout := true
+	api := &testAPI{app: fiber.New()}
+	api.app.Use(func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(c.UserContext())
+		defer cancel()
+		if timeout {
+			s.cancel = cancel
+		} else {
+			s.cancel = nil
+		}
+		c.SetUserContext(ctx)
+		return c.Next()
+	})
+	SetupRoutes(api.app, NewSongsHandlers(log,
+		service.NewSongService(log, s, client),
+		service.NewIdempotencyService(log, s, config.Idempotency{}),
+	))
+
+	body := `{"group": "Muse", "name": "Uprising"}`
+	if status := api.request(t, http.MethodPost, "/api/v1/song", body, nil, HeaderIdempotencyKey, "key-1").StatusCode; status != http.StatusInternalServerError {
+		t.Fatalf("timed out POST /song = %d, want 500", status)
+	}
+
+	// the key was released, so the retry is processed
+	timeout = false
+	if status := api.request(t, http.MethodPost, "/api/v1/song", body, nil, HeaderIdempotencyKey, "key-1").StatusCode; status != http.StatusAccepted {
+		t.Errorf("retried POST /song = %d, want 202", status)
+	}
+}
+
+func TestUpdateSongErrors(t *testing.T) {
+	api := newTestAPI(t)
+
+	for _, name := range []string{"Uprising", "Resistance"} {
+		body := `{"group": "Muse", "name": "` + name + `"}`
+		if status := api.do(t, http.MethodPost, "/api/v1/song?enrich=false", body, nil); status != http.StatusCreated {
+			t.Fatalf("POST /song = %d", status)
+		}
+	}
+
+	resp := api.request(t, http.MethodPut, "/api/v1/song/1", `{"name": "Resistance"}`, nil)
+	if resp.StatusCode != http.StatusConflict || resp.Header.Get("Location") != "/api/v1/song/2" {
+		t.Errorf("PUT /song/1 into a taken name = %d, Location %q, want 409", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if status := api.do(t, http.MethodDelete, "/api/v1/song/2", "", nil); status != http.StatusNoContent {
+		t.Fatalf("DELETE /song/2 = %d", status)
+	}
+	resp = api.request(t, http.MethodPut, "/api/v1/song/1", `{"name": "Resistance"}`, nil)
+	if resp.StatusCode != http.StatusConflict || resp.Header.Get("Location") != "" {
+		t.Errorf("PUT /song/1 into a trashed name = %d, Location %q, want 409 without Location", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if status := api.do(t, http.MethodPut, "/api/v1/song/1", `{"release_date": "07.09.2009"}`, nil); status != http.StatusBadRequest {
+		t.Errorf("PUT /song/1 with a bad date = %d, want 400", status)
+	}
+}
+
+func TestUpsertSong(t *testing.T) {
+	api := newTestAPI(t)
+
+	body := `{"group": "Local Band", "name": "Demo", "link": "https://example.com/demo", "text": "one"}`
+	var upsert dto.UpsertSongDTO
+	resp := api.request(t, http.MethodPut, "/api/v1/song/by-name?enrich=false", body, &upsert)
+	if resp.StatusCode != http.StatusCreated || !upsert.Created || upsert.ID != 1 {
+		t.Fatalf("PUT /song/by-name = %d %+v, want created", resp.StatusCode, upsert)
+	}
+	if location := resp.Header.Get("Location"); location != "/api/v1/song/1" {
+		t.Errorf("Location = %q", location)
+	}
+
+	body = `{"group": "Local Band", "name": "Demo", "release_date": "2020-05-01", "text": "one\n\ntwo"}`
+	upsert = dto.UpsertSongDTO{}
+	if status := api.do(t, http.MethodPut, "/api/v1/song/by-name", body, &upsert); status != http.StatusOK {
+		t.Fatalf("PUT /song/by-name for an existing song = %d", status)
+	}
+	if upsert.Created || upsert.ID != 1 || upsert.Changes == nil || len(upsert.Changes.Fields) != 1 || len(upsert.Changes.Verses) != 1 {
+		t.Errorf("upsert = %+v, want release date and verse 2 changed", upsert)
+	}
+
+	var song dto.SongDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1", "", &song); status != http.StatusOK {
+		t.Fatalf("GET /song/1 = %d", status)
+	}
+	if song.Link != "https://example.com/demo" || len(song.Lyrics) != 2 {
+		t.Errorf("song = %+v, want the link kept and two verses", song)
+	}
+
+	if status := api.do(t, http.MethodPut, "/api/v1/song/by-name", `{"group": "Local Band"}`, nil); status != http.StatusBadRequest {
+		t.Errorf("PUT /song/by-name without a name = %d, want 400", status)
+	}
+}
+
+func TestUpsertPendingSongKeepsStatus(t *testing.T) {
+	api := newTestAPI(t)
+
+	if status := api.do(t, http.MethodPost, "/api/v1/song", `{"group": "Muse", "name": "Uprising"}`, nil); status != http.StatusAccepted {
+		t.Fatalf("POST /song = %d", status)
+	}
+
+	var upsert dto.UpsertSongDTO
+	body := `{"group": "Muse", "name": "Uprising", "link": "https://example.com/mine"}`
+	if status := api.do(t, http.MethodPut, "/api/v1/song/by-name", body, &upsert); status != http.StatusOK {
+		t.Fatalf("PUT /song/by-name = %d", status)
+	}
+	if upsert.Status != "pending_enrichment" {
+		t.Errorf("upsert status = %q, want pending_enrichment", upsert.Status)
+	}
+
+	var enrichment dto.EnrichmentDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1/enrichment", "", &enrichment); status != http.StatusOK {
+		t.Fatalf("GET /song/1/enrichment = %d", status)
+	}
+	if enrichment.Status != "pending_enrichment" || enrichment.JobStatus != "pending" {
+		t.Errorf("enrichment = %+v, want the song still pending its job", enrichment)
+	}
+
+	api.enrich(t)
+	var song dto.SongDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1", "", &song); status != http.StatusOK {
+		t.Fatalf("GET /song/1 = %d", status)
+	}
+	if song.Status != "ready" || song.Link != "https://example.com/mine" || len(song.Lyrics) != 2 {
+		t.Errorf("song = %+v, want it enriched with the upserted link", song)
+	}
+}
+
+func TestSongHistoryAndRevert(t *testing.T) {
+	api := newTestAPI(t)
+
+	body := `{"group": "Local Band", "name": "Demo", "release_date": "2020-05-01", "link": "https://example.com/demo", "text": "one\n\ntwo"}`
+	if status := api.do(t, http.MethodPost, "/api/v1/song?enrich=false", body, nil); status != http.StatusCreated {
+		t.Fatalf("POST /song = %d", status)
+	}
+	resp := api.request(t, http.MethodPut, "/api/v1/song/1", `{"verses": {"2": "spam"}}`, nil, HeaderActor, "vandal")
+	if resp.StatusCode >= http.StatusBadRequest {
+		t.Fatalf("PUT /song/1 = %d", resp.StatusCode)
+	}
+
+	var history dto.HistoryDTO
+	resp = api.request(t, http.MethodGet, "/api/v1/song/1/history", "", &history)
+	if resp.StatusCode != http.StatusOK || history.Total != 1 || resp.Header.Get(HeaderTotalCount) != "1" {
+		t.Fatalf("GET /song/1/history = %d %+v", resp.StatusCode, history)
+	}
+	revision := history.Revisions[0]
+	if revision.Revision != 1 || revision.Actor != "vandal" || len(revision.Verses) != 1 ||
+		revision.Verses[0].Old != "two" || revision.Verses[0].New != "spam" {
+		t.Errorf("revision = %+v", revision)
+	}
+
+	var revert dto.RefreshDTO
+	resp = api.request(t, http.MethodPost, "/api/v1/song/1/revert/1", "", &revert, HeaderActor, "editor")
+	if resp.StatusCode != http.StatusOK || !revert.Changed || len(revert.Verses) != 1 || revert.Verses[0].New != "two" {
+		t.Fatalf("POST /song/1/revert/1 = %d %+v", resp.StatusCode, revert)
+	}
+
+	var song dto.SongDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1", "", &song); status != http.StatusOK {
+		t.Fatalf("GET /song/1 = %d", status)
+	}
+	if len(song.Lyrics) != 2 || song.Lyrics[1].Text != "two" {
+		t.Errorf("verses = %+v, want the edit undone", song.Lyrics)
+	}
+
+	history = dto.HistoryDTO{}
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1/history?limit=1", "", &history); status != http.StatusOK {
+		t.Fatalf("GET /song/1/history = %d", status)
+	}
+	if history.Total != 2 || !history.HasMore || len(history.Revisions) != 1 ||
+		history.Revisions[0].Revision != 2 || history.Revisions[0].Actor != "editor" {
+		t.Errorf("history = %+v, want the revert as the latest revision", history)
+	}
+
+	// changes without X-Actor are still recorded
+	if status := api.do(t, http.MethodPost, "/api/v1/song/1/verses", `{"text": "three"}`, nil); status >= http.StatusBadRequest {
+		t.Fatalf("POST /song/1/verses = %d", status)
+	}
+	history = dto.HistoryDTO{}
+	if api.do(t, http.MethodGet, "/api/v1/song/1/history", "", &history); history.Revisions[0].Actor != AnonymousActor {
+		t.Errorf("latest revision = %+v, want actor %q", history.Revisions[0], AnonymousActor)
+	}
+
+	for target, want := range map[string]int{
+		"/api/v1/song/1/revert/9": http.StatusNotFound,
+		"/api/v1/song/9/revert/1": http.StatusNotFound,
+		"/api/v1/song/1/revert/0": http.StatusBadRequest,
+		"/api/v1/song/1/revert/x": http.StatusBadRequest,
+		"/api/v1/song/x/revert/1": http.StatusBadRequest,
+	} {
+		if status := api.do(t, http.MethodPost, target, "", nil); status != want {
+			t.Errorf("POST %s = %d, want %d", target, status, want)
+		}
+	}
+	if status := api.do(t, http.MethodGet, "/api/v1/song/9/history", "", nil); status != http.StatusNotFound {
+		t.Errorf("GET /song/9/history = %d, want 404", status)
+	}
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	api := newTestAPI(t)
+
+	body := `{"group": "Local Band", "name": "Demo", "release_date": "2020-05-01", "link": "https://example.com/demo", "text": "one\n\ntwo"}`
+	if status := api.do(t, http.MethodPost, "/api/v1/song?enrich=false", body, nil); status != http.StatusCreated {
+		t.Fatalf("POST /song = %d", status)
+	}
+	if status := api.do(t, http.MethodDelete, "/api/v1/song/1", "", nil); status != http.StatusNoContent {
+		t.Fatalf("DELETE /song/1 = %d", status)
+	}
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1", "", nil); status != http.StatusNotFound {
+		t.Errorf("GET /song/1 = %d, want 404 for a deleted song", status)
+	}
+
+	var conflict struct {
+		Error string `json:"error"`
+		ID    uint   `json:"id"`
+	}
+	resp := api.request(t, http.MethodPost, "/api/v1/song?enrich=false", body, &conflict)
+	if resp.StatusCode != http.StatusConflict || conflict.ID != 1 || conflict.Error != "Song is in the trash" {
+		t.Errorf("POST /song = %d %+v, want 409 for the song in the trash", resp.StatusCode, conflict)
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		t.Errorf("Location = %q, want none for the song in the trash", location)
+	}
+	if status := api.do(t, http.MethodPut, "/api/v1/song/by-name", body, nil); status != http.StatusConflict {
+		t.Errorf("PUT /song/by-name = %d, want 409 for the song in the trash", status)
+	}
+
+	var trash dto.TrashDTO
+	resp = api.request(t, http.MethodGet, "/api/v1/trash", "", &trash)
+	if resp.StatusCode != http.StatusOK || trash.Total != 1 || resp.Header.Get(HeaderTotalCount) != "1" {
+		t.Fatalf("GET /trash = %d %+v", resp.StatusCode, trash)
+	}
+	if len(trash.Songs) != 1 || trash.Songs[0].Name != "Demo" || trash.Songs[0].DeletedAt == nil {
+		t.Errorf("trash = %+v, want the deleted song with its deletion time", trash.Songs)
+	}
+
+	var song dto.SongDTO
+	resp = api.request(t, http.MethodPost, "/api/v1/song/1/restore", "", &song)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Location") != "/api/v1/song/1" {
+		t.Fatalf("POST /song/1/restore = %d, Location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if song.Name != "Demo" || song.DeletedAt != nil || len(song.Lyrics) != 2 {
+		t.Errorf("restored song = %+v", song)
+	}
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1", "", nil); status != http.StatusOK {
+		t.Errorf("GET /song/1 = %d after restore", status)
+	}
+
+	for target, want := range map[string]int{
+		"/api/v1/song/1/restore": http.StatusNotFound,
+		"/api/v1/song/9/restore": http.StatusNotFound,
+		"/api/v1/song/x/restore": http.StatusBadRequest,
+	} {
+		if status := api.do(t, http.MethodPost, target, "", nil); status != want {
+			t.Errorf("POST %s = %d, want %d", target, status, want)
+		}
+	}
+}
+
+func TestPendingSongHasNoReleaseDate(t *testing.T) {
+	api := newTestAPI(t)
+
+	if status := api.do(t, http.MethodPost, "/api/v1/song", `{"group": "Muse", "name": "Uprising"}`, nil); status != http.StatusAccepted {
+		t.Fatalf("POST /song = %d", status)
+	}
+
+	var library struct {
+		Songs []map[string]any `json:"songs"`
+	}
+	if status := api.do(t, http.MethodGet, "/api/v1/library", "", &library); status != http.StatusOK {
+		t.Fatalf("GET /library = %d", status)
+	}
+	if len(library.Songs) != 1 {
+		t.Fatalf("library = %+v, want the pending song", library.Songs)
+	}
+	if date, ok := library.Songs[0]["release_date"]; ok {
+		t.Errorf("release_date = %v, want it left out until enrichment", date)
+	}
+
+	api.enrich(t)
+	var song dto.SongDTO
+	if status := api.do(t, http.MethodGet, "/api/v1/song/1", "", &song); status != http.StatusOK {
+		t.Fatalf("GET /song/1 = %d", status)
+	}
+	if song.ReleaseDate == nil || song.ReleaseDate.Format("2006-01-02") != "2009-09-07" {
+		t.Errorf("release_date = %v after enrichment", song.ReleaseDate)
+	}
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"errors"
+	"songs_lib/internal/storage"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// HeaderActor names who makes a change; it is recorded in the song
+	// history.
+	HeaderActor = "X-Actor"
+	// AnonymousActor is recorded for changes made without the X-Actor header.
+	AnonymousActor = "anonymous"
+
+	maxActorLength = 255
+)
+
+// Actor attributes the changes a request makes to the actor in the X-Actor
+// header.
+func (h *SongsHandlers) Actor(c *fiber.Ctx) error {
+	actor := strings.TrimSpace(c.Get(HeaderActor))
+	if actor == "" {
+		actor = AnonymousActor
+	}
+	if len(actor) > maxActorLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid X-Actor",
+		})
+	}
+
+	c.SetUserContext(storage.WithActor(c.UserContext(), actor))
+	return c.Next()
+}
+
+// @Summary История изменений песни
+// @Description Список ревизий песни от новых к старым: кто, когда и что изменил в полях и куплетах. Ревизии записываются при каждом изменении песни, включая загрузку данных из внешнего API
+// @ID get-song-history
+// @Tags Songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param limit query int false "Количество ревизий"
+// @Param offset query int false "Смещение для пагинации"
+// @Success 200 {object} dto.HistoryDTO
+// @Header 200 {integer} X-Total-Count "Общее количество ревизий"
+// @Header 200 {string} Link "Ссылки на первую, предыдущую, следующую и последнюю страницы"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/{id}/history [get]
+func (h *SongsHandlers) GetSongHistory(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
+		})
+	}
+
+	history, err := h.songService.GetHistory(c.UserContext(), uint(songID), c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		if errors.Is(err, storage.ErrSongNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Song not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get song history",
+		})
+	}
+	setPaginationHeaders(c, history.PaginationDTO, "")
+	return c.Status(fiber.StatusOK).JSON(history)
+}
+
+// @Summary Откат песни к ревизии
+// @Description Отменяет ревизию и все более поздние, возвращая песню в состояние до неё. Откат записывается как новая ревизия, поэтому его тоже можно отменить
+// @ID revert-song
+// @Tags Songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param rev path int true "Номер ревизии"
+// @Param X-Actor header string false "Автор изменения для истории, по умолчанию anonymous"
+// @Success 200 {object} dto.RefreshDTO
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Песня с прежними группой и названием уже существует"
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/{id}/revert/{rev} [post]
+func (h *SongsHandlers) RevertSong(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
+		})
+	}
+	revision, err := strconv.ParseUint(c.Params("rev"), 10, 0)
+	if err != nil || revision == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid revision",
+		})
+	}
+
+	revert, err := h.songService.RevertSong(c.UserContext(), uint(songID), uint(revision))
+	if err != nil {
+		var exists *storage.ErrSongExists
+		switch {
+		case errors.Is(err, storage.ErrSongNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Song not found",
+			})
+		case errors.Is(err, storage.ErrRevisionNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Revision not found",
+			})
+		case errors.As(err, &exists):
+			return songConflict(c, exists)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revert song",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(revert)
+}
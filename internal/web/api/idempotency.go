@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	songService "songs_lib/internal/service"
+	"songs_lib/pkg/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed marks a response replayed for a repeated key.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotent replays the stored response when a request is repeated with the
+// same Idempotency-Key header. Server errors are not stored, so a request
+// that failed can be retried with the same key. The key is completed or
+// released even when the request timed out.
+func (h *SongsHandlers) Idempotent(c *fiber.Ctx) error {
+	key := c.Get(HeaderIdempotencyKey)
+	if key == "" {
+		return c.Next()
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid Idempotency-Key",
+		})
+	}
+
+	record, err := h.idempotency.Begin(c.UserContext(), key, fingerprint(c))
+	if err != nil {
+		if errors.Is(err, songService.ErrIdempotencyKeyReused) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": "Idempotency-Key was used for a different request",
+			})
+		}
+		if errors.Is(err, songService.ErrIdempotencyKeyInProgress) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Request with this Idempotency-Key is in progress",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check Idempotency-Key",
+		})
+	}
+
+	if record != nil {
+		if record.Location != "" {
+			c.Location(record.Location)
+		}
+		c.Set(HeaderIdempotentReplayed, "true")
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(record.StatusCode).Send(record.Body)
+	}
+
+	// the request context is cancelled once the request times out
+	ctx := context.WithoutCancel(c.UserContext())
+
+	if err := c.Next(); err != nil {
+		h.releaseIdempotencyKey(ctx, key)
+		return err
+	}
+
+	resp := c.Response()
+	if resp.StatusCode() >= fiber.StatusInternalServerError {
+		h.releaseIdempotencyKey(ctx, key)
+		return nil
+	}
+	if err := h.idempotency.Complete(ctx, key, resp.StatusCode(), c.GetRespHeader(fiber.HeaderLocation), resp.Body()); err != nil {
+		// without the stored response a retry has to be processed again
+		h.releaseIdempotencyKey(ctx, key)
+	}
+	return nil
+}
+
+// releaseIdempotencyKey lets the request be retried with the same key. If
+// that fails, a retry takes the key over once its lease ends.
+func (h *SongsHandlers) releaseIdempotencyKey(ctx context.Context, key string) {
+	if err := h.idempotency.Release(ctx, key); err != nil {
+		h.log.Warn("Idempotency-Key is kept until its lease ends", slog.String("key", key), logger.Err(err))
+	}
+}
+
+// fingerprint identifies a request by its method, path, query and body.
+func fingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	for _, part := range [][]byte{
+		[]byte(c.Method()),
+		c.Request().URI().RequestURI(),
+		c.Body(),
+	} {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
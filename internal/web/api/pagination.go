@@ -0,0 +1,65 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"songs_lib/internal/dto"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const HeaderTotalCount = "X-Total-Count"
+
+// setPaginationHeaders sets X-Total-Count and a Link header (RFC 8288) with
+// the first, prev, next and last pages of the current request. Pages are
+// addressed by offset. When the request pages by cursor, next follows
+// nextCursor and prev and last are omitted, since cursors only go forward.
+func setPaginationHeaders(c *fiber.Ctx, page dto.PaginationDTO, nextCursor string) {
+	c.Set(HeaderTotalCount, strconv.Itoa(page.Total))
+	if page.Limit <= 0 {
+		return
+	}
+
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		return
+	}
+	byCursor := query.Get("cursor") != ""
+
+	link := func(rel string, set func(q url.Values)) string {
+		q := make(url.Values, len(query))
+		for key, values := range query {
+			q[key] = values
+		}
+		set(q)
+		return fmt.Sprintf(`<%s%s?%s>; rel="%s"`, c.BaseURL(), c.Path(), q.Encode(), rel)
+	}
+	atOffset := func(offset int) func(q url.Values) {
+		return func(q url.Values) {
+			q.Del("cursor")
+			q.Set("offset", strconv.Itoa(offset))
+		}
+	}
+
+	links := []string{link("first", atOffset(0))}
+	if !byCursor && page.Offset > 0 {
+		links = append(links, link("prev", atOffset(max(page.Offset-page.Limit, 0))))
+	}
+	if page.HasMore {
+		if byCursor && nextCursor != "" {
+			links = append(links, link("next", func(q url.Values) {
+				q.Del("offset")
+				q.Set("cursor", nextCursor)
+			}))
+		} else {
+			links = append(links, link("next", atOffset(page.Offset+page.Limit)))
+		}
+	}
+	if !byCursor && page.Total > 0 {
+		links = append(links, link("last", atOffset((page.Total-1)/page.Limit*page.Limit)))
+	}
+
+	c.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
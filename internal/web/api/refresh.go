@@ -0,0 +1,68 @@
+package web
+
+import (
+	"errors"
+	songService "songs_lib/internal/service"
+	"songs_lib/internal/storage"
+	external "songs_lib/internal/web/external"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Обновление песни из внешнего API
+// @Description Повторно запрашивает ссылку, дату релиза и текст песни во внешнем API, применяет отличия от сохранённых данных в одной транзакции и возвращает список изменений. Пустые значения из API не стирают сохранённые данные
+// @ID refresh-song
+// @Tags Songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Success 200 {object} dto.RefreshDTO
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Данные песни ещё загружаются в фоне"
+// @Failure 422 {object} map[string]interface{} "Внешний API не знает эту песню"
+// @Failure 500 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/song/{id}/refresh [post]
+func (h *SongsHandlers) RefreshSong(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
+		})
+	}
+
+	refresh, err := h.songService.RefreshSong(c.UserContext(), uint(songID))
+	if err != nil {
+		if errors.Is(err, storage.ErrSongNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Song not found",
+			})
+		}
+		if errors.Is(err, storage.ErrSongPendingEnrichment) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Song is pending enrichment",
+			})
+		}
+		if errors.Is(err, external.ErrSongNotFound) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": "Song is unknown to the external API",
+			})
+		}
+		if errors.Is(err, external.ErrCircuitOpen) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "External API is unavailable",
+			})
+		}
+		if errors.Is(err, songService.ErrExternalAPI) {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "Failed to fetch song details",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh song",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(refresh)
+}
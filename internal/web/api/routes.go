@@ -9,9 +9,23 @@ import (
 
 func SetupRoutes(app *fiber.App, handlers *SongsHandlers) {
 	app.Get("/swagger/*", swagger.WrapHandler)
-	app.Post("/api/v1/song", handlers.AddSong)
+	app.Use("/api", handlers.Actor)
+	app.Post("/api/v1/song", handlers.Idempotent, handlers.AddSong)
+	app.Get("/api/v1/song/:id", handlers.GetSong)
 	app.Delete("/api/v1/song/:id", handlers.DeleteSong)
 	app.Get("/api/v1/lyrics/:id", handlers.GetLyrics)
+	// registered before /song/:id, which would match it too
+	app.Put("/api/v1/song/by-name", handlers.UpsertSong)
 	app.Put("/api/v1/song/:id", handlers.UpdateSong)
 	app.Get("/api/v1/library", handlers.GetLibrary)
+	app.Get("/api/v1/search", handlers.Search)
+	app.Post("/api/v1/song/:id/verses", handlers.AddVerse)
+	app.Put("/api/v1/song/:id/verses/order", handlers.ReorderVerses)
+	app.Delete("/api/v1/song/:id/verses/:verse", handlers.DeleteVerse)
+	app.Get("/api/v1/song/:id/enrichment", handlers.GetEnrichment)
+	app.Post("/api/v1/song/:id/refresh", handlers.RefreshSong)
+	app.Get("/api/v1/song/:id/history", handlers.GetSongHistory)
+	app.Post("/api/v1/song/:id/revert/:rev", handlers.RevertSong)
+	app.Get("/api/v1/trash", handlers.GetTrash)
+	app.Post("/api/v1/song/:id/restore", handlers.RestoreSong)
 }
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"errors"
+	"songs_lib/internal/storage"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Корзина
+// @Description Список удалённых песен от недавно удалённых к давним. Песни хранятся в корзине до окончательного удаления по истечении срока хранения
+// @ID get-trash
+// @Tags Songs
+// @Produce json
+// @Param limit query int false "Количество песен"
+// @Param offset query int false "Смещение для пагинации"
+// @Success 200 {object} dto.TrashDTO
+// @Header 200 {integer} X-Total-Count "Общее количество песен в корзине"
+// @Header 200 {string} Link "Ссылки на первую, предыдущую, следующую и последнюю страницы"
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/trash [get]
+func (h *SongsHandlers) GetTrash(c *fiber.Ctx) error {
+	trash, err := h.songService.GetTrash(c.UserContext(), c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get trash",
+		})
+	}
+	setPaginationHeaders(c, trash.PaginationDTO, "")
+	return c.Status(fiber.StatusOK).JSON(trash)
+}
+
+// @Summary Восстановление песни из корзины
+// @Description Возвращает удалённую песню вместе с куплетами и историей изменений
+// @ID restore-song
+// @Tags Songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Success 200 {object} dto.SongDTO
+// @Header 200 {string} Location "Адрес песни"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{} "Песни нет в корзине"
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/{id}/restore [post]
+func (h *SongsHandlers) RestoreSong(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
+		})
+	}
+
+	song, err := h.songService.RestoreSong(c.UserContext(), uint(songID))
+	if err != nil {
+		if errors.Is(err, storage.ErrSongNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Song not found in trash",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to restore song",
+		})
+	}
+
+	c.Location(songLocation(song.ID))
+	return c.Status(fiber.StatusOK).JSON(song)
+}
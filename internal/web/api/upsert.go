@@ -0,0 +1,63 @@
+package web
+
+import (
+	"errors"
+	"songs_lib/internal/model"
+	"songs_lib/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Создание или обновление песни по названию
+// @Description Создаёт песню с указанными группой и названием, как POST /api/v1/song, или, если она уже есть, заменяет переданными значениями сохранённые дату релиза, ссылку и текст. Пустые значения не меняют сохранённые данные
+// @ID upsert-song
+// @Tags Songs
+// @Accept json
+// @Produce json
+// @Param song body dto.CreateSongRequest true "Song"
+// @Param enrich query bool false "Загружать недостающие данные новой песни из внешнего API, по умолчанию true"
+// @Success 200 {object} dto.UpsertSongDTO "Песня обновлена"
+// @Success 201 {object} dto.UpsertSongDTO "Песня создана со всеми данными"
+// @Success 202 {object} dto.UpsertSongDTO "Песня создана, недостающие данные загружаются в фоне"
+// @Header 200 {string} Location "Адрес песни"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Песня находится в корзине"
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/by-name [put]
+func (h *SongsHandlers) UpsertSong(c *fiber.Ctx) error {
+	req, releaseDate, err := h.parseCreateSongRequest(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	upsert, err := h.songService.UpsertSong(
+		c.UserContext(),
+		req.Group,
+		req.Name,
+		req.Link,
+		releaseDate,
+		req.Text,
+		c.QueryBool("enrich", true),
+	)
+	if err != nil {
+		var exists *storage.ErrSongExists
+		if errors.As(err, &exists) {
+			return songConflict(c, exists)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to upsert song",
+		})
+	}
+
+	c.Location(songLocation(upsert.ID))
+	switch {
+	case !upsert.Created:
+		return c.Status(fiber.StatusOK).JSON(upsert)
+	case upsert.Status == string(model.SongStatusPendingEnrichment):
+		return c.Status(fiber.StatusAccepted).JSON(upsert)
+	default:
+		return c.Status(fiber.StatusCreated).JSON(upsert)
+	}
+}
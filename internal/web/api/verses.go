@@ -0,0 +1,152 @@
+package web
+
+import (
+	"errors"
+	"songs_lib/internal/dto"
+	"songs_lib/internal/storage"
+	"songs_lib/pkg/logger"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Добавление куплета
+// @Description Вставка куплета на указанную позицию или в конец песни, последующие куплеты сдвигаются
+// @ID add-verse
+// @Tags Lyrics
+// @Accept json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param verse body dto.CreateVerseRequest true "Verse"
+// @Success 201 {object} dto.LyricsDTO
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/{id}/verses [post]
+func (h *SongsHandlers) AddVerse(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
+		})
+	}
+
+	var req dto.CreateVerseRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Debug("Failed to parse request body", logger.Err(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.log.Debug("Failed to validate request body", logger.Err(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	verse, err := h.songService.AddVerse(c.UserContext(), uint(songID), req.Position, req.Text)
+	if err != nil {
+		return h.verseError(c, err, "Failed to add verse")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(verse)
+}
+
+// @Summary Удаление куплета
+// @Description Удаление куплета по номеру, последующие куплеты перенумеровываются
+// @ID delete-verse
+// @Tags Lyrics
+// @Param id path int true "Song ID"
+// @Param verse path int true "Verse number"
+// @Success 204 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/{id}/verses/{verse} [delete]
+func (h *SongsHandlers) DeleteVerse(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
+		})
+	}
+
+	verseNumber, err := strconv.Atoi(c.Params("verse"))
+	if err != nil || verseNumber <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid verse number",
+		})
+	}
+
+	if err := h.songService.DeleteVerse(c.UserContext(), uint(songID), uint(verseNumber)); err != nil {
+		return h.verseError(c, err, "Failed to delete verse")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// @Summary Изменение порядка куплетов
+// @Description Новый порядок задается списком текущих номеров всех куплетов
+// @ID reorder-verses
+// @Tags Lyrics
+// @Accept json
+// @Param id path int true "Song ID"
+// @Param order body dto.ReorderVersesRequest true "Verse order"
+// @Success 204 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/song/{id}/verses/order [put]
+func (h *SongsHandlers) ReorderVerses(c *fiber.Ctx) error {
+	songID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid song ID",
+		})
+	}
+
+	var req dto.ReorderVersesRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Debug("Failed to parse request body", logger.Err(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.log.Debug("Failed to validate request body", logger.Err(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.songService.ReorderVerses(c.UserContext(), uint(songID), req.Order); err != nil {
+		return h.verseError(c, err, "Failed to reorder verses")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *SongsHandlers) verseError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, storage.ErrSongNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Song not found",
+		})
+	case errors.Is(err, storage.ErrVerseNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Verse not found",
+		})
+	case errors.Is(err, storage.ErrInvalidVersePosition),
+		errors.Is(err, storage.ErrInvalidVerseOrder):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"songs_lib/config"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache remembers the details a provider returned for a song, and for a
+// shorter time that it doesn't know the song. Concurrent requests for the
+// same song share one call to the provider.
+type Cache struct {
+	provider    SongInfoProvider
+	ttl         time.Duration
+	notFoundTTL time.Duration
+	size        int
+	now         func() time.Time
+
+	group   singleflight.Group
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	details   *FetchData
+	err       error
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+func NewCache(provider SongInfoProvider, cfg config.ExternalAPI) *Cache {
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = 10000
+	}
+
+	return &Cache{
+		provider:    provider,
+		ttl:         cfg.CacheTTL,
+		notFoundTTL: cfg.CacheNotFoundTTL,
+		size:        cfg.CacheSize,
+		now:         time.Now,
+		entries:     make(map[string]cacheEntry),
+	}
+}
+
+func (c *Cache) Name() string {
+	return c.provider.Name()
+}
+
+// FetchSong returns the cached result for the song or asks the provider.
+// Only complete details and not-found errors are cached; other errors, and
+// details returned with ErrPartialData, are fetched again by the next call.
+func (c *Cache) FetchSong(ctx context.Context, group, song string) (*FetchData, error) {
+	key := cacheKey(group, song)
+	if entry, ok := c.get(key); ok {
+		return copyFetchData(entry.details), entry.err
+	}
+
+	// the shared call must not be canceled by the caller that started it
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		details, err := c.provider.FetchSong(context.WithoutCancel(ctx), group, song)
+		switch {
+		case err == nil:
+			c.set(key, cacheEntry{details: details}, c.ttl)
+		case errors.Is(err, ErrSongNotFound):
+			c.set(key, cacheEntry{err: err}, c.notFoundTTL)
+		}
+		return details, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		details, _ := res.Val.(*FetchData)
+		return copyFetchData(details), res.Err
+	}
+}
+
+func (c *Cache) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *Cache) set(key string, entry cacheEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		var oldest string
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldest == "" || e.storedAt.Before(c.entries[oldest].storedAt) {
+				oldest = k
+			}
+		}
+		// still full, drop the oldest entry
+		if len(c.entries) >= c.size {
+			delete(c.entries, oldest)
+		}
+	}
+
+	entry.storedAt = now
+	entry.expiresAt = now.Add(ttl)
+	c.entries[key] = entry
+}
+
+// cacheKey ignores case and differences in whitespace.
+func cacheKey(group, song string) string {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	return normalize(group) + "\x00" + normalize(song)
+}
+
+// copyFetchData keeps callers from changing the cached details.
+func copyFetchData(details *FetchData) *FetchData {
+	if details == nil {
+		return nil
+	}
+	copied := *details
+	return &copied
+}
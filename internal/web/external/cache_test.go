@@ -0,0 +1,171 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"songs_lib/config"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingProvider counts its calls and, when release is set, blocks them
+// until it is closed.
+type countingProvider struct {
+	data    *FetchData
+	err     error
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (p *countingProvider) Name() string {
+	return "counting"
+}
+
+func (p *countingProvider) FetchSong(ctx context.Context, group, song string) (*FetchData, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		<-p.release
+	}
+	return p.data, p.err
+}
+
+// newTestCache returns a cache whose clock only moves when the returned
+// function is called.
+func newTestCache(provider SongInfoProvider, size int) (*Cache, func(time.Duration)) {
+	cache := NewCache(provider, config.ExternalAPI{
+		CacheTTL:         10 * time.Minute,
+		CacheNotFoundTTL: time.Minute,
+		CacheSize:        size,
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	return cache, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCacheSharesConcurrentCalls(t *testing.T) {
+	provider := &countingProvider{data: &FetchData{Link: "https://example.com"}, release: make(chan struct{})}
+	cache, _ := newTestCache(provider, 10)
+
+	const lookups = 10
+	var wg sync.WaitGroup
+	for i := 0; i < lookups; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			details, err := cache.FetchSong(context.Background(), "Muse", "Uprising")
+			if err != nil || details == nil || details.Link != "https://example.com" {
+				t.Errorf("FetchSong = %+v, %v", details, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("provider called %d times for %d lookups, want 1", calls, lookups)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	provider := &countingProvider{data: &FetchData{Link: "https://example.com"}}
+	cache, advance := newTestCache(provider, 10)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.FetchSong(context.Background(), "Muse", "Uprising"); err != nil {
+			t.Fatalf("FetchSong: %v", err)
+		}
+	}
+	// the key ignores case and whitespace
+	if _, err := cache.FetchSong(context.Background(), " muse", "UPRISING "); err != nil {
+		t.Fatalf("FetchSong: %v", err)
+	}
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("provider called %d times, want 1", calls)
+	}
+
+	advance(10 * time.Minute)
+	if _, err := cache.FetchSong(context.Background(), "Muse", "Uprising"); err != nil {
+		t.Fatalf("FetchSong: %v", err)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("provider called %d times after the TTL, want 2", calls)
+	}
+}
+
+func TestCacheNotFound(t *testing.T) {
+	provider := &countingProvider{err: &StatusError{StatusCode: http.StatusNotFound}}
+	cache, advance := newTestCache(provider, 10)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.FetchSong(context.Background(), "Muse", "Unknown"); !errors.Is(err, ErrSongNotFound) {
+			t.Fatalf("FetchSong error = %v, want ErrSongNotFound", err)
+		}
+		advance(30 * time.Second)
+	}
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("provider called %d times within the not found TTL, want 1", calls)
+	}
+
+	if _, err := cache.FetchSong(context.Background(), "Muse", "Unknown"); !errors.Is(err, ErrSongNotFound) {
+		t.Fatalf("FetchSong error = %v, want ErrSongNotFound", err)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("provider called %d times after the not found TTL, want 2", calls)
+	}
+}
+
+func TestCacheSkipsFailures(t *testing.T) {
+	provider := &countingProvider{err: ErrCircuitOpen}
+	cache, _ := newTestCache(provider, 10)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.FetchSong(context.Background(), "Muse", "Uprising"); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("FetchSong error = %v, want ErrCircuitOpen", err)
+		}
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("provider called %d times, want every failure retried", calls)
+	}
+}
+
+func TestCacheSkipsPartialData(t *testing.T) {
+	primary := &countingProvider{err: ErrCircuitOpen}
+	fallback := &countingProvider{data: &FetchData{Link: "https://fallback"}}
+	cache, _ := newTestCache(newTestChain(primary, fallback), 10)
+
+	for i := 0; i < 2; i++ {
+		details, err := cache.FetchSong(context.Background(), "Muse", "Uprising")
+		if !errors.Is(err, ErrPartialData) || details == nil || details.Link != "https://fallback" {
+			t.Fatalf("FetchSong = %+v, %v, want the fallback details with ErrPartialData", details, err)
+		}
+	}
+	if calls := primary.calls.Load(); calls != 2 {
+		t.Errorf("primary called %d times, want partial details fetched again", calls)
+	}
+}
+
+func TestCacheEvictsOldest(t *testing.T) {
+	provider := &countingProvider{data: &FetchData{Link: "https://example.com"}}
+	cache, advance := newTestCache(provider, 2)
+
+	for _, song := range []string{"Uprising", "Resistance", "Madness"} {
+		if _, err := cache.FetchSong(context.Background(), "Muse", song); err != nil {
+			t.Fatalf("FetchSong: %v", err)
+		}
+		advance(time.Second)
+	}
+
+	// Resistance and Madness are still cached, Uprising was evicted
+	for _, song := range []string{"Resistance", "Madness", "Uprising"} {
+		if _, err := cache.FetchSong(context.Background(), "Muse", song); err != nil {
+			t.Fatalf("FetchSong: %v", err)
+		}
+	}
+	if calls := provider.calls.Load(); calls != 4 {
+		t.Errorf("provider called %d times, want the oldest entry fetched again", calls)
+	}
+}
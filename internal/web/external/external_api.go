@@ -1,11 +1,17 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"songs_lib/config"
+	"songs_lib/pkg/logger"
+	"time"
 )
 
 type FetchData struct {
@@ -14,29 +20,155 @@ type FetchData struct {
 	Text        string `json:"text"`
 }
 
-func FetchSong(externalAPI, group, song string) (*FetchData, error) {
-	url := fmt.Sprintf(
+// StatusError is returned when the external API answers with a non-200
+// status. Only 5xx statuses are retried.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("external API returned status code %d", e.StatusCode)
+}
+
+func (e *StatusError) Is(target error) bool {
+	return target == ErrSongNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Client fetches song details from the external API with per-request
+// timeouts, retries and a circuit breaker.
+type Client struct {
+	log            *slog.Logger
+	baseURL        string
+	http           *http.Client
+	maxRetries     int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	breaker        *circuitBreaker
+}
+
+func NewClient(log *slog.Logger, cfg config.ExternalAPI) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.RetryBaseDelay <= 0 {
+		cfg.RetryBaseDelay = 200 * time.Millisecond
+	}
+	if cfg.RetryMaxDelay < cfg.RetryBaseDelay {
+		cfg.RetryMaxDelay = cfg.RetryBaseDelay
+	}
+
+	return &Client{
+		log:            log,
+		baseURL:        cfg.URL,
+		http:           &http.Client{Timeout: cfg.Timeout},
+		maxRetries:     max(cfg.MaxRetries, 0),
+		retryBaseDelay: cfg.RetryBaseDelay,
+		retryMaxDelay:  cfg.RetryMaxDelay,
+		breaker:        newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}
+}
+
+func (c *Client) Name() string {
+	return c.baseURL
+}
+
+func (c *Client) FetchSong(ctx context.Context, group, song string) (*FetchData, error) {
+	infoURL := fmt.Sprintf(
 		"%s/info?group=%s&song=%s",
-		externalAPI,
+		c.baseURL,
 		url.QueryEscape(group),
 		url.QueryEscape(song),
 	)
 
-	resp, err := http.Get(url)
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if err := c.breaker.allow(); err != nil {
+			if lastErr != nil {
+				// the circuit opened while retrying
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		details, err := c.fetch(ctx, infoURL)
+		if err == nil {
+			c.breaker.success()
+			return details, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			c.breaker.abort()
+			return nil, ctxErr
+		}
+		if !retryable(err) {
+			// the upstream is up, it just doesn't like the request
+			c.breaker.success()
+			return nil, err
+		}
+
+		c.breaker.failure()
+		if attempt >= c.maxRetries {
+			return nil, err
+		}
+		lastErr = err
+
+		delay := c.backoff(attempt)
+		c.log.Debug("Retrying external API request",
+			slog.Int("attempt", attempt+1),
+			slog.Duration("delay", delay),
+			logger.Err(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) fetch(ctx context.Context, infoURL string) (*FetchData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, infoURL, nil)
 	if err != nil {
-		return nil, errors.New("failed to connect to external API")
+		return nil, fmt.Errorf("failed to create external API request: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external API: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("external API returned status code %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	var details FetchData
 	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
-		return nil, errors.New("failed to decode external API response")
+		return nil, fmt.Errorf("failed to decode external API response: %w", err)
 	}
 
 	return &details, nil
+}
 
+// backoff returns the delay before retry attempt+1: the base delay doubled
+// for every attempt, capped and with up to 50% jitter taken off.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retryMaxDelay
+	if attempt < 32 {
+		delay = min(c.retryBaseDelay<<attempt, c.retryMaxDelay)
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// retryable reports whether err is a network error or a 5xx status.
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	// http.Client.Do wraps every transport failure in *url.Error
+	var netErr *url.Error
+	return errors.As(err, &netErr)
 }
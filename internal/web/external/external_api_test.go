@@ -0,0 +1,153 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"songs_lib/config"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, cfg config.ExternalAPI) (*Client, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg.URL = server.URL
+	cfg.RetryBaseDelay = time.Millisecond
+	cfg.RetryMaxDelay = time.Millisecond
+	return NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg), &calls
+}
+
+func TestFetchSongRetries(t *testing.T) {
+	var failures atomic.Int32
+	client, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Query().Get("group") != "Muse" || r.URL.Query().Get("song") != "Uprising" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		io.WriteString(w, `{"releaseDate": "07.09.2009", "link": "https://example.com", "text": "verse"}`)
+	}, config.ExternalAPI{MaxRetries: 2})
+
+	details, err := client.FetchSong(context.Background(), "Muse", "Uprising")
+	if err != nil {
+		t.Fatalf("FetchSong: %v", err)
+	}
+	if details.ReleaseDate != "07.09.2009" || details.Text != "verse" {
+		t.Errorf("FetchSong = %+v", details)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("upstream called %d times, want 3", got)
+	}
+}
+
+func TestFetchSongGivesUp(t *testing.T) {
+	client, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}, config.ExternalAPI{MaxRetries: 1})
+
+	_, err := client.FetchSong(context.Background(), "Muse", "Uprising")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("FetchSong error = %v, want status 500", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("upstream called %d times, want 2", got)
+	}
+}
+
+func TestFetchSongNoRetryOnClientError(t *testing.T) {
+	client, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}, config.ExternalAPI{MaxRetries: 3, BreakerThreshold: 1})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.FetchSong(context.Background(), "Muse", "Unknown")
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("FetchSong error = %v, want status 404", err)
+		}
+	}
+	// 4xx responses neither retry nor open the circuit
+	if got := calls.Load(); got != 2 {
+		t.Errorf("upstream called %d times, want 2", got)
+	}
+}
+
+func TestFetchSongTimeout(t *testing.T) {
+	client, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}, config.ExternalAPI{Timeout: 20 * time.Millisecond, MaxRetries: 1})
+
+	start := time.Now()
+	if _, err := client.FetchSong(context.Background(), "Muse", "Uprising"); err == nil {
+		t.Fatal("FetchSong succeeded, want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("FetchSong took %v, want the request timeout to apply", elapsed)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("upstream called %d times, want 2", got)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	client, calls := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, `{"releaseDate": "07.09.2009"}`)
+	}, config.ExternalAPI{BreakerThreshold: 2, BreakerCooldown: time.Minute})
+
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.FetchSong(context.Background(), "Muse", "Uprising"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("FetchSong #%d error = %v, want upstream error", i, err)
+		}
+	}
+
+	if _, err := client.FetchSong(context.Background(), "Muse", "Uprising"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("FetchSong error = %v, want ErrCircuitOpen", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("upstream called %d times while open, want 2", got)
+	}
+
+	// after the cooldown a failed probe opens the circuit again
+	now = now.Add(time.Minute)
+	if _, err := client.FetchSong(context.Background(), "Muse", "Uprising"); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe error = %v, want upstream error", err)
+	}
+	if _, err := client.FetchSong(context.Background(), "Muse", "Uprising"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("FetchSong error = %v, want ErrCircuitOpen", err)
+	}
+
+	// and a successful probe closes it
+	healthy.Store(true)
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := client.FetchSong(context.Background(), "Muse", "Uprising"); err != nil {
+			t.Fatalf("FetchSong #%d after recovery: %v", i, err)
+		}
+	}
+}
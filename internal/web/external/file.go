@@ -0,0 +1,73 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileProvider serves song details from a local JSON file: an array of
+// objects with group, song, releaseDate, link and text, the format of the
+// seed command.
+type FileProvider struct {
+	path  string
+	songs map[fileKey]FetchData
+}
+
+type fileKey struct {
+	group string
+	song  string
+}
+
+type fileSong struct {
+	Group string `json:"group"`
+	Song  string `json:"song"`
+	FetchData
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read song info file: %w", err)
+	}
+
+	var songs []fileSong
+	if err := json.Unmarshal(data, &songs); err != nil {
+		return nil, fmt.Errorf("failed to decode song info file %s: %w", path, err)
+	}
+
+	provider := &FileProvider{
+		path:  path,
+		songs: make(map[fileKey]FetchData, len(songs)),
+	}
+	for _, song := range songs {
+		provider.songs[newFileKey(song.Group, song.Song)] = song.FetchData
+	}
+	return provider, nil
+}
+
+func newFileKey(group, song string) fileKey {
+	return fileKey{
+		group: strings.ToLower(strings.TrimSpace(group)),
+		song:  strings.ToLower(strings.TrimSpace(song)),
+	}
+}
+
+func (p *FileProvider) Name() string {
+	return "file:" + p.path
+}
+
+// FetchSong looks the song up ignoring case and surrounding spaces.
+func (p *FileProvider) FetchSong(ctx context.Context, group, song string) (*FetchData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	details, ok := p.songs[newFileKey(group, song)]
+	if !ok {
+		return nil, ErrSongNotFound
+	}
+	return &details, nil
+}
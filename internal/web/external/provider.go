@@ -0,0 +1,139 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"songs_lib/config"
+	"songs_lib/pkg/logger"
+	"strings"
+)
+
+// ErrSongNotFound is returned when a provider has no details of the song.
+// A 404 StatusError matches it too.
+var ErrSongNotFound = errors.New("song not found")
+
+// ErrPartialData is returned by a chain together with the details it got
+// when some of its providers failed, so the details may be incomplete or
+// differ from what the failed providers would have returned.
+var ErrPartialData = errors.New("some song info providers failed")
+
+// ErrNoProviders is returned by a chain without providers.
+var ErrNoProviders = errors.New("no song info providers configured")
+
+// SongInfoProvider is a source of song details.
+type SongInfoProvider interface {
+	// Name identifies the provider in logs.
+	Name() string
+	FetchSong(ctx context.Context, group, song string) (*FetchData, error)
+}
+
+// Chain asks its providers in order and takes every field from the first one
+// that has it, so a secondary source only fills the gaps of the primary.
+type Chain struct {
+	log       *slog.Logger
+	providers []SongInfoProvider
+}
+
+func NewChain(log *slog.Logger, providers ...SongInfoProvider) *Chain {
+	return &Chain{
+		log:       log,
+		providers: providers,
+	}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// NewProviders builds the chain configured in cfg: the primary API, the
+// fallback API and the local file, each only when set.
+func NewProviders(log *slog.Logger, cfg config.ExternalAPI) (*Chain, error) {
+	var providers []SongInfoProvider
+	if cfg.URL != "" {
+		providers = append(providers, NewClient(log, cfg))
+	}
+	if cfg.FallbackURL != "" {
+		fallback := cfg
+		fallback.URL = cfg.FallbackURL
+		providers = append(providers, NewClient(log, fallback))
+	}
+	if cfg.File != "" {
+		file, err := NewFileProvider(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, file)
+	}
+
+	return NewChain(log, providers...), nil
+}
+
+// FetchSong returns the merged details once every field is filled or all
+// providers were asked. If a provider failed on the way, the details are
+// returned with ErrPartialData. When no provider returned anything it fails
+// with a 404 StatusError if none of them knows the song, otherwise with the
+// errors of the providers that failed.
+func (c *Chain) FetchSong(ctx context.Context, group, song string) (*FetchData, error) {
+	if len(c.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	var merged *FetchData
+	var errs []error
+
+	for _, provider := range c.providers {
+		details, err := provider.FetchSong(ctx, group, song)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			if !errors.Is(err, ErrSongNotFound) {
+				c.log.Warn("Song info provider failed",
+					slog.String("provider", provider.Name()),
+					logger.Err(err),
+				)
+				errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			}
+			continue
+		}
+
+		if merged == nil {
+			merged = &FetchData{}
+		}
+		mergeFetchData(merged, details)
+		if merged.Link != "" && merged.ReleaseDate != "" && merged.Text != "" {
+			break
+		}
+	}
+
+	if merged != nil && len(errs) > 0 {
+		return merged, fmt.Errorf("%w: %w", ErrPartialData, errors.Join(errs...))
+	}
+	if merged != nil {
+		return merged, nil
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, &StatusError{StatusCode: http.StatusNotFound}
+}
+
+// mergeFetchData fills the empty fields of dst from src.
+func mergeFetchData(dst, src *FetchData) {
+	if dst.Link == "" {
+		dst.Link = src.Link
+	}
+	if dst.ReleaseDate == "" {
+		dst.ReleaseDate = src.ReleaseDate
+	}
+	if dst.Text == "" {
+		dst.Text = src.Text
+	}
+}
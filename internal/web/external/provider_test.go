@@ -0,0 +1,109 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type stubProvider struct {
+	name  string
+	data  *FetchData
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) FetchSong(ctx context.Context, group, song string) (*FetchData, error) {
+	p.calls++
+	return p.data, p.err
+}
+
+func newTestChain(providers ...SongInfoProvider) *Chain {
+	return NewChain(slog.New(slog.NewTextHandler(io.Discard, nil)), providers...)
+}
+
+func TestChainMergesFields(t *testing.T) {
+	primary := &stubProvider{name: "primary", data: &FetchData{Link: "https://primary"}}
+	fallback := &stubProvider{name: "fallback", data: &FetchData{Link: "https://fallback", ReleaseDate: "07.09.2009"}}
+	file := &stubProvider{name: "file", data: &FetchData{ReleaseDate: "01.01.2000", Text: "verse"}}
+	unused := &stubProvider{name: "unused", data: &FetchData{Text: "other"}}
+
+	details, err := newTestChain(primary, fallback, file, unused).FetchSong(context.Background(), "Muse", "Uprising")
+	if err != nil {
+		t.Fatalf("FetchSong: %v", err)
+	}
+	want := FetchData{Link: "https://primary", ReleaseDate: "07.09.2009", Text: "verse"}
+	if *details != want {
+		t.Errorf("FetchSong = %+v, want %+v", *details, want)
+	}
+	if unused.calls != 0 {
+		t.Errorf("provider after a complete result was called %d times", unused.calls)
+	}
+}
+
+func TestChainSkipsFailedProvider(t *testing.T) {
+	primary := &stubProvider{name: "primary", err: ErrCircuitOpen}
+	fallback := &stubProvider{name: "fallback", data: &FetchData{Link: "https://fallback"}}
+
+	details, err := newTestChain(primary, fallback).FetchSong(context.Background(), "Muse", "Uprising")
+	if !errors.Is(err, ErrPartialData) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("FetchSong error = %v, want ErrPartialData", err)
+	}
+	if details == nil || details.Link != "https://fallback" {
+		t.Errorf("FetchSong = %+v, want the fallback link", details)
+	}
+}
+
+func TestChainErrors(t *testing.T) {
+	notFound := &stubProvider{name: "file", err: ErrSongNotFound}
+	_, err := newTestChain(&stubProvider{err: &StatusError{StatusCode: http.StatusNotFound}}, notFound).
+		FetchSong(context.Background(), "Muse", "Unknown")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("FetchSong error = %v, want status 404", err)
+	}
+
+	_, err = newTestChain(&stubProvider{err: ErrCircuitOpen}, notFound).
+		FetchSong(context.Background(), "Muse", "Unknown")
+	if !errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrSongNotFound) {
+		t.Errorf("FetchSong error = %v, want ErrCircuitOpen", err)
+	}
+
+	if _, err := newTestChain().FetchSong(context.Background(), "Muse", "Uprising"); !errors.Is(err, ErrNoProviders) {
+		t.Errorf("FetchSong error = %v, want ErrNoProviders", err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "songs.json")
+	content := `[{"group": "Muse", "song": "Uprising", "releaseDate": "07.09.2009", "link": "https://example.com", "text": "verse"}]`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileProvider: %v", err)
+	}
+
+	details, err := provider.FetchSong(context.Background(), " muse", "UPRISING")
+	if err != nil {
+		t.Fatalf("FetchSong: %v", err)
+	}
+	if details.ReleaseDate != "07.09.2009" || details.Text != "verse" {
+		t.Errorf("FetchSong = %+v", details)
+	}
+
+	if _, err := provider.FetchSong(context.Background(), "Muse", "Unknown"); !errors.Is(err, ErrSongNotFound) {
+		t.Errorf("FetchSong error = %v, want ErrSongNotFound", err)
+	}
+}